@@ -2,22 +2,50 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"zone.digit.tommie/internal/infra/export"
 	"zone.digit.tommie/internal/proxy"
 	"zone.digit.tommie/internal/storage"
 )
 
+// ExportedArtifact is an exported exchange's encoded bytes plus the filename
+// and MIME type the frontend should save it as. Bytes are base64-encoded
+// since Wails marshals []byte that way over IPC regardless.
+type ExportedArtifact struct {
+	DataBase64 string `json:"dataBase64"`
+	Filename   string `json:"filename"`
+	MimeType   string `json:"mimeType"`
+}
+
+// tlsProfileStore is the storage.Database store name TLS profiles are kept in.
+const tlsProfileStore = "tlsProfiles"
+
 // App struct holds the application state and provides IPC bindings.
 type App struct {
-	ctx context.Context
-	db  *storage.Database
+	ctx   context.Context
+	db    *storage.Database
+	token string
+
+	streamsMu sync.Mutex
+	streams   map[string]context.CancelFunc
 }
 
 // NewApp creates a new App instance.
 func NewApp() *App {
-	return &App{}
+	return &App{
+		streams: make(map[string]context.CancelFunc),
+	}
 }
 
 // startup is called when the app starts. It initializes the database.
@@ -35,13 +63,92 @@ func (a *App) startup(ctx context.Context) {
 		panic("failed to create app directory: " + err.Error())
 	}
 
+	passphrase, err := storagePassphrase(appDir)
+	if err != nil {
+		panic("failed to resolve storage passphrase: " + err.Error())
+	}
+
 	dbPath := filepath.Join(appDir, "storage.db")
-	db, err := storage.New(dbPath)
+	db, err := storage.New(dbPath, storage.Options{Passphrase: passphrase})
 	if err != nil {
 		panic("failed to initialize database: " + err.Error())
 	}
-
 	a.db = db
+
+	token, err := generateStorageToken()
+	if err != nil {
+		panic("failed to generate storage token: " + err.Error())
+	}
+	a.token = token
+
+	if _, err := a.storageContext(tlsProfileStore); err != nil {
+		panic("failed to register storage: " + err.Error())
+	}
+}
+
+// generateStorageToken produces the desktop app's own capability token for
+// internal/storage's ACL. It's generated fresh every startup and never
+// persisted or exposed over IPC - the app process itself is the only holder
+// and the only caller these IPC bindings accept requests from.
+func generateStorageToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate storage token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// storageContext returns a context carrying the app's own storage token,
+// granting it every capability on store first. The desktop app is the sole
+// legitimate caller of the Storage*/TLSProfile* IPC bindings below, so any
+// store name the frontend chooses becomes usable under the app's own token
+// without a separate grant step - the ACL's job is denying callers who never
+// present that token at all, not gatekeeping the app from itself.
+func (a *App) storageContext(store string) (context.Context, error) {
+	for _, cap := range []storage.Capability{storage.CapRead, storage.CapWrite, storage.CapClear} {
+		if err := a.db.Grant(store, cap, a.token); err != nil {
+			return nil, err
+		}
+	}
+	return storage.WithToken(context.Background(), a.token), nil
+}
+
+// storagePassphraseEnvVar lets anyone running the desktop build behind a
+// real secret store (a password manager integration, an OS keychain helper
+// invoked before launch, etc.) supply the storage passphrase directly,
+// instead of relying on the generated-and-persisted fallback below.
+const storagePassphraseEnvVar = "TOMMIE_STORAGE_PASSPHRASE"
+
+// storagePassphraseFileName is where storagePassphrase persists a generated
+// passphrase when storagePassphraseEnvVar isn't set.
+const storagePassphraseFileName = "storage.passphrase"
+
+// storagePassphrase returns the passphrase used to derive the storage
+// database's key-encryption-key. This build has no interactive unlock
+// prompt yet, so absent storagePassphraseEnvVar a passphrase is generated
+// once and persisted in appDir - no stronger against local access than the
+// previous sibling-key-file design, but internal/storage itself now
+// supports the real passphrase+Argon2id model as soon as an unlock prompt
+// is wired up in the frontend.
+func storagePassphrase(appDir string) (string, error) {
+	if pass := os.Getenv(storagePassphraseEnvVar); pass != "" {
+		return pass, nil
+	}
+
+	path := filepath.Join(appDir, storagePassphraseFileName)
+	if data, err := os.ReadFile(path); err == nil {
+		return string(data), nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate storage passphrase: %w", err)
+	}
+	pass := hex.EncodeToString(buf)
+	if err := os.WriteFile(path, []byte(pass), 0600); err != nil {
+		return "", fmt.Errorf("failed to persist storage passphrase: %w", err)
+	}
+	return pass, nil
 }
 
 // shutdown is called when the app is closing.
@@ -57,32 +164,309 @@ func (a *App) ProxyRequest(request proxy.ProxyRequest) proxy.ProxyResponse {
 	return proxy.ExecuteRequest(request)
 }
 
+// streamHeadersEvent and streamChunkEvent are the payloads emitted over Wails
+// runtime events for a streaming request; streamID lets the frontend match
+// events to the stream it started.
+type streamHeadersEvent struct {
+	StreamID string `json:"streamId"`
+	proxy.StreamHeaders
+}
+
+type streamChunkEvent struct {
+	StreamID string `json:"streamId"`
+	proxy.StreamChunk
+}
+
+// ProxyRequestStream starts a streaming request and returns immediately with
+// a stream ID. Progress is reported asynchronously over Wails runtime events
+// proxy:stream:headers, proxy:stream:chunk, proxy:stream:done and
+// proxy:stream:error, each payload carrying the stream ID.
+func (a *App) ProxyRequestStream(request proxy.ProxyRequest) (string, error) {
+	streamID, err := newStreamID()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+
+	a.streamsMu.Lock()
+	a.streams[streamID] = cancel
+	a.streamsMu.Unlock()
+
+	go func() {
+		defer a.endStream(streamID)
+
+		err := proxy.StreamRequest(ctx, request,
+			func(headers proxy.StreamHeaders) {
+				wailsRuntime.EventsEmit(a.ctx, "proxy:stream:headers", streamHeadersEvent{StreamID: streamID, StreamHeaders: headers})
+			},
+			func(chunk proxy.StreamChunk) error {
+				wailsRuntime.EventsEmit(a.ctx, "proxy:stream:chunk", streamChunkEvent{StreamID: streamID, StreamChunk: chunk})
+				return nil
+			},
+		)
+
+		if err != nil {
+			wailsRuntime.EventsEmit(a.ctx, "proxy:stream:error", map[string]string{"streamId": streamID, "message": err.Error()})
+			return
+		}
+		wailsRuntime.EventsEmit(a.ctx, "proxy:stream:done", map[string]string{"streamId": streamID})
+	}()
+
+	return streamID, nil
+}
+
+// ProxyStreamCancel cancels an in-flight streaming request started with ProxyRequestStream.
+func (a *App) ProxyStreamCancel(streamID string) {
+	a.streamsMu.Lock()
+	cancel, ok := a.streams[streamID]
+	a.streamsMu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
+
+func (a *App) endStream(streamID string) {
+	a.streamsMu.Lock()
+	delete(a.streams, streamID)
+	a.streamsMu.Unlock()
+}
+
+// newStreamID generates a random identifier for a streaming request.
+func newStreamID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// loadTestRecordEvent and loadTestDoneEvent are the payloads emitted over
+// Wails runtime events for a load-test run; runID lets the frontend match
+// events to the run it started.
+type loadTestRecordEvent struct {
+	RunID string `json:"runId"`
+	proxy.ReportRecord
+}
+
+type loadTestDoneEvent struct {
+	RunID string `json:"runId"`
+	proxy.LoadSummary
+}
+
+// ProxyLoadTest starts a rate-limited load test against request and returns
+// immediately with a run ID. Per-request results stream over the Wails
+// runtime event proxy:loadtest:record and the final summary is delivered on
+// proxy:loadtest:done, each payload carrying the run ID.
+func (a *App) ProxyLoadTest(request proxy.ProxyRequest, opts proxy.LoadOptions) (string, error) {
+	runID, err := newStreamID()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+
+	a.streamsMu.Lock()
+	a.streams[runID] = cancel
+	a.streamsMu.Unlock()
+
+	go func() {
+		defer a.endStream(runID)
+
+		summary := proxy.RunLoadTest(ctx, request, opts, func(record proxy.ReportRecord) {
+			wailsRuntime.EventsEmit(a.ctx, "proxy:loadtest:record", loadTestRecordEvent{RunID: runID, ReportRecord: record})
+		})
+
+		wailsRuntime.EventsEmit(a.ctx, "proxy:loadtest:done", loadTestDoneEvent{RunID: runID, LoadSummary: summary})
+	}()
+
+	return runID, nil
+}
+
+// ProxyLoadTestCancel cancels an in-flight load test started with ProxyLoadTest.
+func (a *App) ProxyLoadTestCancel(runID string) {
+	a.ProxyStreamCancel(runID)
+}
+
 // StorageGet retrieves a value from storage.
 func (a *App) StorageGet(store, key string) (*string, error) {
-	return a.db.Get(store, key)
+	ctx, err := a.storageContext(store)
+	if err != nil {
+		return nil, err
+	}
+	return a.db.Get(ctx, store, key)
 }
 
 // StorageSet stores a value in storage.
 func (a *App) StorageSet(store, key, value string) error {
-	return a.db.Set(store, key, value)
+	ctx, err := a.storageContext(store)
+	if err != nil {
+		return err
+	}
+	return a.db.Set(ctx, store, key, value)
 }
 
 // StorageRemove deletes a value from storage.
 func (a *App) StorageRemove(store, key string) error {
-	return a.db.Remove(store, key)
+	ctx, err := a.storageContext(store)
+	if err != nil {
+		return err
+	}
+	return a.db.Remove(ctx, store, key)
 }
 
 // StorageHas checks if a key exists in storage.
 func (a *App) StorageHas(store, key string) (bool, error) {
-	return a.db.Has(store, key)
+	ctx, err := a.storageContext(store)
+	if err != nil {
+		return false, err
+	}
+	return a.db.Has(ctx, store, key)
 }
 
 // StorageClear removes all values in a store.
 func (a *App) StorageClear(store string) error {
-	return a.db.Clear(store)
+	ctx, err := a.storageContext(store)
+	if err != nil {
+		return err
+	}
+	return a.db.Clear(ctx, store)
 }
 
 // StorageKeys returns all keys in a store.
 func (a *App) StorageKeys(store string) ([]string, error) {
-	return a.db.Keys(store)
+	ctx, err := a.storageContext(store)
+	if err != nil {
+		return nil, err
+	}
+	return a.db.Keys(ctx, store)
+}
+
+// TLSProfileSave persists a named TLS profile (client certificate, custom CA,
+// etc.) so it can be bound to requests by ID later.
+func (a *App) TLSProfileSave(profile proxy.TLSProfile) error {
+	if profile.ID == "" {
+		return fmt.Errorf("TLS profile ID is required")
+	}
+
+	encoded, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to encode TLS profile: %w", err)
+	}
+
+	ctx, err := a.storageContext(tlsProfileStore)
+	if err != nil {
+		return err
+	}
+	return a.db.Set(ctx, tlsProfileStore, profile.ID, string(encoded))
+}
+
+// TLSProfileLoad retrieves a previously saved TLS profile by ID.
+func (a *App) TLSProfileLoad(id string) (*proxy.TLSProfile, error) {
+	ctx, err := a.storageContext(tlsProfileStore)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := a.db.Get(ctx, tlsProfileStore, id)
+	if err != nil {
+		return nil, err
+	}
+	if encoded == nil {
+		return nil, nil
+	}
+
+	var profile proxy.TLSProfile
+	if err := json.Unmarshal([]byte(*encoded), &profile); err != nil {
+		return nil, fmt.Errorf("failed to decode TLS profile: %w", err)
+	}
+	return &profile, nil
+}
+
+// TLSProfileList returns every saved TLS profile.
+func (a *App) TLSProfileList() ([]proxy.TLSProfile, error) {
+	ctx, err := a.storageContext(tlsProfileStore)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := a.db.Keys(ctx, tlsProfileStore)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]proxy.TLSProfile, 0, len(ids))
+	for _, id := range ids {
+		profile, err := a.TLSProfileLoad(id)
+		if err != nil {
+			return nil, err
+		}
+		if profile != nil {
+			profiles = append(profiles, *profile)
+		}
+	}
+	return profiles, nil
+}
+
+// TLSProfileDelete removes a saved TLS profile.
+func (a *App) TLSProfileDelete(id string) error {
+	ctx, err := a.storageContext(tlsProfileStore)
+	if err != nil {
+		return err
+	}
+	return a.db.Remove(ctx, tlsProfileStore, id)
+}
+
+// ExportExchange renders a completed request/response pair as a curl
+// command, a JetBrains/VS Code .http file, or a standalone HAR document,
+// selected by format ("curl", "http" or "har").
+func (a *App) ExportExchange(request proxy.ProxyRequest, response proxy.ProxyResponse, format string) (*ExportedArtifact, error) {
+	if response.Data == nil {
+		return nil, fmt.Errorf("response has no data to export")
+	}
+
+	exporter, err := proxy.ExportFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	data, filename, mimeType, err := exporter.Export(proxy.ToExchange(request, *response.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to export exchange: %w", err)
+	}
+
+	return &ExportedArtifact{
+		DataBase64: base64.StdEncoding.EncodeToString(data),
+		Filename:   filename,
+		MimeType:   mimeType,
+	}, nil
+}
+
+// ExportBulkHAR packages many completed request/response pairs captured at
+// capturedAtUnix (one Unix timestamp per pair) into a single HAR log zip, as
+// returned by ExportExchange's "har" format for a whole session at once.
+func (a *App) ExportBulkHAR(requests []proxy.ProxyRequest, responses []proxy.ProxyResponse, capturedAtUnix []int64) (*ExportedArtifact, error) {
+	if len(requests) != len(responses) || len(requests) != len(capturedAtUnix) {
+		return nil, fmt.Errorf("requests, responses and capturedAtUnix must have the same length")
+	}
+
+	exchanges := make([]export.Exchange, 0, len(requests))
+	capturedAt := make([]time.Time, 0, len(requests))
+	for i, response := range responses {
+		if response.Data == nil {
+			return nil, fmt.Errorf("response %d has no data to export", i)
+		}
+		exchanges = append(exchanges, proxy.ToExchange(requests[i], *response.Data))
+		capturedAt = append(capturedAt, time.Unix(capturedAtUnix[i], 0).UTC())
+	}
+
+	data, err := export.BulkHAR(exchanges, capturedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export bulk HAR: %w", err)
+	}
+
+	return &ExportedArtifact{
+		DataBase64: base64.StdEncoding.EncodeToString(data),
+		Filename:   "requests-export.zip",
+		MimeType:   "application/zip",
+	}, nil
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"zone.digit.tommie/internal/exchanges"
+	"zone.digit.tommie/internal/infra/export"
+	"zone.digit.tommie/internal/proxy"
+)
+
+// requireAPIToken wraps next so it only runs once the request presents
+// token via "Authorization: Bearer <token>". Captured exchanges can carry
+// Authorization headers and cookies from the sites they were recorded
+// against, so an empty token (EXCHANGE_API_TOKEN unset) disables next
+// entirely rather than leaving it reachable over a network the
+// TLS-optional listener was never meant to be the only thing guarding.
+func requireAPIToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeJSONError(w, http.StatusServiceUnavailable, "exchange export API is disabled: set EXCHANGE_API_TOKEN to enable it")
+			return
+		}
+
+		const prefix = "Bearer "
+		presented := strings.TrimPrefix(r.Header.Get("Authorization"), prefix)
+		if !strings.HasPrefix(r.Header.Get("Authorization"), prefix) ||
+			subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// recordExchangeRequest is the body the browser extension posts once a
+// request/response pair has completed, so it becomes exportable below.
+type recordExchangeRequest struct {
+	Request  proxy.ProxyRequest `json:"request"`
+	Response proxy.ResponseData `json:"response"`
+}
+
+// recordExchangeHandler accepts POST /api/requests, storing the captured
+// exchange so it can later be fetched via the export endpoints below. The
+// server never executes requests itself (see the package doc comment in
+// main.go), so this is the only way an exchange enters the store.
+func recordExchangeHandler(store *exchanges.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var body recordExchangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+
+		id, err := store.Add(body.Request, body.Response, time.Now())
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": id})
+	}
+}
+
+// exportExchangeHandler serves GET /api/requests/{id}/export?format=curl|http|har,
+// rendering a previously recorded exchange the same way the desktop app's
+// ExportExchange IPC binding does.
+func exportExchangeHandler(store *exchanges.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/requests/"), "/export")
+		if !ok || id == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		entry, ok := store.Get(id)
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, "no exchange recorded under that id")
+			return
+		}
+
+		exporter, err := proxy.ExportFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		data, filename, mimeType, err := exporter.Export(proxy.ToExchange(entry.Request, entry.Response))
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to export exchange: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", mimeType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Write(data)
+	}
+}
+
+// bulkExportHandler serves GET /api/requests/export.har, streaming a zip of
+// every exchange recorded so far - the server-side equivalent of the desktop
+// app's ExportBulkHAR binding.
+func bulkExportHandler(store *exchanges.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		all := store.All()
+		exchangeList := make([]export.Exchange, 0, len(all))
+		capturedAt := make([]time.Time, 0, len(all))
+		for _, entry := range all {
+			exchangeList = append(exchangeList, proxy.ToExchange(entry.Request, entry.Response))
+			capturedAt = append(capturedAt, entry.CapturedAt)
+		}
+
+		data, err := export.BulkHAR(exchangeList, capturedAt)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to export bulk HAR: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="requests-export.zip"`)
+		w.Write(data)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAPITokenDisablesEndpointWithNoConfiguredToken(t *testing.T) {
+	called := false
+	handler := requireAPIToken("", func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/requests/export.har", nil))
+
+	if called {
+		t.Fatal("expected next to never run when no token is configured")
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRequireAPITokenRejectsMissingOrWrongBearer(t *testing.T) {
+	handler := requireAPIToken("secret", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("expected next to never run without the correct bearer token")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/requests/export.har", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing header: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/requests/export.har", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRequireAPITokenAllowsCorrectBearer(t *testing.T) {
+	called := false
+	handler := requireAPIToken("secret", func(w http.ResponseWriter, r *http.Request) { called = true })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/requests/export.har", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if !called {
+		t.Fatal("expected next to run with the correct bearer token")
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -1,28 +1,79 @@
 // Package main provides the web server entry point.
-// This server ONLY serves static frontend files - no proxy API.
-// Proxy requests are handled by the browser extension when using the hosted version.
+// This server serves static frontend files and a small identity API - no
+// proxy API. Proxy requests are handled by the browser extension when using
+// the hosted version, which posts completed exchanges to /api/requests so
+// they can be exported as curl/.http/HAR via the endpoints in exchanges.go.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 
 	"zone.digit.tommie/internal/config"
+	"zone.digit.tommie/internal/exchanges"
+	"zone.digit.tommie/internal/identity"
 	"zone.digit.tommie/internal/static"
 )
 
 func main() {
 	cfg := config.Load()
 
-	// Serve static files only - no API endpoints
-	http.Handle("/", static.Handler())
+	mux := http.NewServeMux()
+	mux.Handle("/", static.Handler())
+
+	exchangeStore := exchanges.NewStore()
+	mux.HandleFunc("/api/requests/export.har", requireAPIToken(cfg.ExchangeAPIToken, bulkExportHandler(exchangeStore)))
+	mux.HandleFunc("/api/requests", requireAPIToken(cfg.ExchangeAPIToken, recordExchangeHandler(exchangeStore)))
+	mux.HandleFunc("/api/requests/", requireAPIToken(cfg.ExchangeAPIToken, exportExchangeHandler(exchangeStore)))
+
+	if cfg.ExchangeAPIToken == "" {
+		log.Printf("Note: EXCHANGE_API_TOKEN is not set, so /api/requests is disabled (refuses every request).")
+	}
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
-	log.Printf("Project Tommie web server starting on http://localhost%s", addr)
-	log.Printf("Note: This server only serves static files. Proxy requests are handled by the browser extension.")
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	if !cfg.TLSEnabled {
+		mux.HandleFunc("/api/cert/fingerprint", certFingerprintHandler(nil))
+
+		log.Printf("Project Tommie web server starting on http://localhost%s", addr)
+		log.Printf("Note: This server only serves static files, the identity API and the exchange export API. Proxy requests are handled by the browser extension.")
+
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Fatalf("Server failed: %v", err)
+		}
+		return
+	}
+
+	cert, err := identity.EnsureCert(cfg.CertDir, cfg.TLSHost)
+	if err != nil {
+		log.Fatalf("Failed to set up identity certificate: %v", err)
+	}
+	mux.HandleFunc("/api/cert/fingerprint", certFingerprintHandler(cert))
+
+	log.Printf("Project Tommie web server starting on https://%s%s", cfg.TLSHost, addr)
+	log.Printf("Identity certificate fingerprint (sha256): %s", cert.Fingerprint)
+	log.Printf("Note: This server only serves static files, the identity API and the exchange export API. Proxy requests are handled by the browser extension.")
+
+	if err := http.ListenAndServeTLS(addr, cert.CertPath, cert.KeyPath, mux); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+// certFingerprintHandler serves the identity certificate's SHA-256
+// fingerprint so users can pin or import it, e.g. when accessing the
+// visualizer over the LAN from a phone. cert is nil when TLS is disabled.
+func certFingerprintHandler(cert *identity.Cert) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if cert == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "TLS is not enabled"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"fingerprint": cert.Fingerprint})
+	}
+}
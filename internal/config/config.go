@@ -3,24 +3,56 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
 const (
 	// DefaultPort is the default HTTP server port.
 	DefaultPort = 3000
+	// DefaultCertDirName is the directory name identity certs are persisted
+	// under, relative to the user's config directory.
+	DefaultCertDirName = "tommie/certs"
 )
 
 // Config holds the application configuration.
 type Config struct {
 	Port int
+
+	// TLSEnabled switches the server to serve over HTTPS using a self-signed
+	// identity certificate instead of plain HTTP.
+	TLSEnabled bool
+	// TLSHost is the hostname the identity certificate's SANs should cover,
+	// in addition to localhost and the detected LAN IP.
+	TLSHost string
+	// CertDir is where the identity key and certificate are persisted.
+	CertDir string
+
+	// ExchangeAPIToken, if set, is the bearer token required to record or
+	// export captured exchanges over /api/requests. Captured exchanges can
+	// carry Authorization headers and cookies, so this is left empty (the
+	// endpoints refuse all requests) rather than defaulting to some
+	// generated-but-unprotected value.
+	ExchangeAPIToken string
 }
 
 // Load loads configuration from environment variables.
 func Load() *Config {
 	return &Config{
-		Port: getEnvInt("PORT", DefaultPort),
+		Port:             getEnvInt("PORT", DefaultPort),
+		TLSEnabled:       getEnvBool("TLS_ENABLED", false),
+		TLSHost:          getEnvString("TLS_HOST", "localhost"),
+		CertDir:          getEnvString("CERT_DIR", defaultCertDir()),
+		ExchangeAPIToken: getEnvString("EXCHANGE_API_TOKEN", ""),
+	}
+}
+
+func defaultCertDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return DefaultCertDirName
 	}
+	return filepath.Join(dir, DefaultCertDirName)
 }
 
 func getEnvInt(key string, defaultVal int) int {
@@ -31,3 +63,19 @@ func getEnvInt(key string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getEnvBool(key string, defaultVal bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+func getEnvString(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
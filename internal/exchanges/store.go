@@ -0,0 +1,100 @@
+// Package exchanges holds completed request/response pairs captured by the
+// browser extension so the web server can offer the same curl/.http/HAR
+// export endpoints the desktop app exposes over IPC. The server itself never
+// executes requests (see cmd/server), so exchanges only exist once the
+// extension reports them here.
+package exchanges
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"zone.digit.tommie/internal/proxy"
+)
+
+// MaxEntries caps how many captured exchanges are kept in memory at once;
+// the oldest entry is evicted to make room for a new one past this limit.
+const MaxEntries = 500
+
+// Entry is a single captured request/response pair plus when it was recorded.
+type Entry struct {
+	ID         string
+	Request    proxy.ProxyRequest
+	Response   proxy.ResponseData
+	CapturedAt time.Time
+}
+
+// Store is an in-memory, bounded, insertion-ordered collection of Entry
+// values, safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	order   []string
+	entries map[string]Entry
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{
+		entries: make(map[string]Entry),
+	}
+}
+
+// Add records a captured exchange and returns the ID it was stored under.
+func (s *Store) Add(request proxy.ProxyRequest, response proxy.ResponseData, capturedAt time.Time) (string, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
+	entry := Entry{
+		ID:         id,
+		Request:    request,
+		Response:   response,
+		CapturedAt: capturedAt,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.order) >= MaxEntries {
+		oldest := s.order[0]
+		s.order = s.order[1:]
+		delete(s.entries, oldest)
+	}
+	s.order = append(s.order, id)
+	s.entries[id] = entry
+
+	return id, nil
+}
+
+// Get looks up a previously recorded exchange by ID.
+func (s *Store) Get(id string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	return entry, ok
+}
+
+// All returns every captured exchange in the order it was recorded.
+func (s *Store) All() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Entry, 0, len(s.order))
+	for _, id := range s.order {
+		out = append(out, s.entries[id])
+	}
+	return out
+}
+
+func generateID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate exchange id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,54 @@
+package exchanges
+
+import (
+	"testing"
+	"time"
+
+	"zone.digit.tommie/internal/proxy"
+)
+
+func TestAddAndGetRoundTrip(t *testing.T) {
+	store := NewStore()
+
+	id, err := store.Add(proxy.ProxyRequest{Method: "GET", URL: "https://example.test"}, proxy.ResponseData{}, time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	entry, ok := store.Get(id)
+	if !ok {
+		t.Fatal("expected the recorded exchange to be found")
+	}
+	if entry.Request.URL != "https://example.test" {
+		t.Fatalf("got URL %q, want %q", entry.Request.URL, "https://example.test")
+	}
+}
+
+func TestGetMissingIDReturnsFalse(t *testing.T) {
+	store := NewStore()
+	if _, ok := store.Get("does-not-exist"); ok {
+		t.Fatal("expected Get to report no match for an unknown id")
+	}
+}
+
+func TestAddEvictsOldestBeyondMaxEntries(t *testing.T) {
+	store := NewStore()
+
+	var firstID string
+	for i := 0; i < MaxEntries+1; i++ {
+		id, err := store.Add(proxy.ProxyRequest{Method: "GET", URL: "https://example.test"}, proxy.ResponseData{}, time.Unix(0, 0))
+		if err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if i == 0 {
+			firstID = id
+		}
+	}
+
+	if _, ok := store.Get(firstID); ok {
+		t.Fatal("expected the oldest entry to be evicted once MaxEntries was exceeded")
+	}
+	if got := len(store.All()); got != MaxEntries {
+		t.Fatalf("got %d entries, want %d", got, MaxEntries)
+	}
+}
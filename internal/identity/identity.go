@@ -0,0 +1,229 @@
+// Package identity manages the server's self-signed TLS identity: a
+// persistent ECDSA key and a self-signed certificate whose serial number is
+// derived from that key. Both are persisted and reused across restarts; a
+// regenerated certificate keeps the serial but has new validity dates, a new
+// signature and so a new fingerprint.
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	keyFileName  = "identity.key"
+	certFileName = "identity.crt"
+
+	certLifetime = 365 * 24 * time.Hour
+	renewWithin  = 30 * 24 * time.Hour
+)
+
+// Cert is the server's self-signed identity: a certificate/key pair persisted
+// on disk, plus the SHA-256 fingerprint of the certificate for TOFU pinning.
+type Cert struct {
+	CertPath    string
+	KeyPath     string
+	Fingerprint string
+}
+
+// EnsureCert loads the identity certificate persisted under certDir,
+// generating it on first run. The certificate is regenerated - reusing the
+// same key, so its serial number stays stable, though its fingerprint
+// changes - whenever its SANs no longer cover host or it expires within 30
+// days.
+func EnsureCert(certDir, host string) (*Cert, error) {
+	if err := os.MkdirAll(certDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cert directory: %w", err)
+	}
+
+	keyPath := filepath.Join(certDir, keyFileName)
+	certPath := filepath.Join(certDir, certFileName)
+
+	key, err := loadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identity key: %w", err)
+	}
+
+	dnsNames, ips := detectSANs(host)
+
+	if der, ok := loadValidCert(certPath, dnsNames, ips); ok {
+		return certResult(certPath, keyPath, der), nil
+	}
+
+	der, err := generateCert(key, dnsNames, ips)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity cert: %w", err)
+	}
+	if err := writePEMFile(certPath, "CERTIFICATE", der); err != nil {
+		return nil, fmt.Errorf("failed to persist identity cert: %w", err)
+	}
+
+	return certResult(certPath, keyPath, der), nil
+}
+
+// loadOrCreateKey loads the persisted ECDSA identity key, generating and
+// persisting a new P-256 key on first run.
+func loadOrCreateKey(keyPath string) (*ecdsa.PrivateKey, error) {
+	if data, err := os.ReadFile(keyPath); err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("invalid identity key PEM at %s", keyPath)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writePEMFile(keyPath, "EC PRIVATE KEY", der); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// writePEMFile writes a single PEM block to path, creating or truncating it.
+func writePEMFile(path, blockType string, der []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return pem.Encode(f, &pem.Block{Type: blockType, Bytes: der})
+}
+
+// loadValidCert returns the persisted certificate's DER bytes if it exists,
+// covers every SAN in dnsNames/ips, and doesn't expire within renewWithin.
+func loadValidCert(certPath string, dnsNames []string, ips []net.IP) ([]byte, bool) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, false
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, false
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, false
+	}
+
+	if time.Until(cert.NotAfter) < renewWithin {
+		return nil, false
+	}
+	if !sameSANs(cert, dnsNames, ips) {
+		return nil, false
+	}
+
+	return block.Bytes, true
+}
+
+func sameSANs(cert *x509.Certificate, dnsNames []string, ips []net.IP) bool {
+	if len(cert.DNSNames) != len(dnsNames) || len(cert.IPAddresses) != len(ips) {
+		return false
+	}
+	for i, name := range dnsNames {
+		if cert.DNSNames[i] != name {
+			return false
+		}
+	}
+	for i, ip := range ips {
+		if !cert.IPAddresses[i].Equal(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// generateCert builds a self-signed certificate for key, with a serial number
+// derived from a hash of its public key so the serial stays stable across
+// regenerations. The validity dates and signature differ on every call, so
+// the certificate's fingerprint does not.
+func generateCert(key *ecdsa.PrivateKey, dnsNames []string, ips []net.IP) ([]byte, error) {
+	pubBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(pubBytes)
+	serial := new(big.Int).SetBytes(digest[:16])
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Project Tommie local identity"},
+		NotBefore:             now.Add(-1 * time.Hour),
+		NotAfter:              now.Add(certLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+	}
+
+	return x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+}
+
+// detectSANs builds the DNS and IP SANs the identity certificate should
+// cover: localhost and the configured host, plus loopback and the machine's
+// outbound LAN address so the UI is reachable from other devices.
+func detectSANs(host string) ([]string, []net.IP) {
+	dnsNames := []string{"localhost"}
+	if host != "" && host != "localhost" {
+		dnsNames = append(dnsNames, host)
+	}
+
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if lan := outboundIP(); lan != nil {
+		ips = append(ips, lan)
+	}
+
+	return dnsNames, ips
+}
+
+// outboundIP detects the machine's LAN IP via the UDP-dial trick: dialing a
+// public address never sends a packet but makes the OS pick a local route,
+// whose source address is the outbound interface IP.
+func outboundIP() net.IP {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	return addr.IP
+}
+
+func certResult(certPath, keyPath string, der []byte) *Cert {
+	sum := sha256.Sum256(der)
+	return &Cert{
+		CertPath:    certPath,
+		KeyPath:     keyPath,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+}
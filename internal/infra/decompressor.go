@@ -4,9 +4,29 @@ import (
 	"bytes"
 	"compress/flate"
 	"compress/gzip"
+	"fmt"
 	"io"
+	"strings"
+	"sync"
 
 	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	// DefaultMaxDecompressedBytes caps how much output a single decompression
+	// is allowed to produce, regardless of the ratio guard below.
+	DefaultMaxDecompressedBytes = 100 * 1024 * 1024 // 100 MiB
+
+	// DefaultMaxRatio is the maximum decompressed/compressed size ratio
+	// tolerated once ratioGuardThreshold bytes have been produced.
+	DefaultMaxRatio = 1000
+
+	// ratioGuardThreshold is how much output is allowed before the ratio
+	// guard kicks in, so small legitimately-high-ratio payloads (e.g. a
+	// repetitive JSON body) aren't rejected.
+	ratioGuardThreshold = 1 * 1024 * 1024 // 1 MiB
 )
 
 // DecompressResult contains the result of a decompression operation.
@@ -16,33 +36,83 @@ type DecompressResult struct {
 	DecompressedSize int
 }
 
-// Decompress decompresses data based on the content-encoding.
-func Decompress(data []byte, encoding string) (*DecompressResult, error) {
-	switch encoding {
-	case "gzip":
-		return decompressGzip(data)
-	case "deflate":
-		return decompressDeflate(data)
-	case "br":
-		return decompressBrotli(data)
-	default:
-		// No compression or unknown encoding - return as-is
-		return &DecompressResult{
-			Data:             data,
-			CompressedSize:   len(data),
-			DecompressedSize: len(data),
-		}, nil
+// DecoderFactory wraps a raw reader with a decompressing reader for one
+// content-encoding token.
+type DecoderFactory func(io.Reader) (io.ReadCloser, error)
+
+// DecoderRegistry maps Content-Encoding tokens to decoder factories, with
+// built-in support for gzip, deflate, br, zstd and lz4. Callers can register
+// additional decoders via RegisterDecoder, e.g. for a custom capture filter.
+type DecoderRegistry struct {
+	mu       sync.RWMutex
+	decoders map[string]DecoderFactory
+
+	// MaxDecompressedBytes caps total decompressed output; 0 disables the cap.
+	MaxDecompressedBytes int64
+	// MaxRatio caps decompressed/compressed size once ratioGuardThreshold
+	// bytes have been produced; 0 disables the ratio guard.
+	MaxRatio float64
+}
+
+// NewDecoderRegistry creates a registry with the built-in decoders registered
+// and the default size/ratio guards applied.
+func NewDecoderRegistry() *DecoderRegistry {
+	r := &DecoderRegistry{
+		decoders:             make(map[string]DecoderFactory),
+		MaxDecompressedBytes: DefaultMaxDecompressedBytes,
+		MaxRatio:             DefaultMaxRatio,
 	}
+
+	r.RegisterDecoder("gzip", func(rd io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(rd)
+	})
+	r.RegisterDecoder("deflate", func(rd io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(rd), nil
+	})
+	r.RegisterDecoder("br", func(rd io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(rd)), nil
+	})
+	r.RegisterDecoder("zstd", func(rd io.Reader) (io.ReadCloser, error) {
+		dec, err := zstd.NewReader(rd)
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReadCloser{dec}, nil
+	})
+	r.RegisterDecoder("lz4", func(rd io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(lz4.NewReader(rd)), nil
+	})
+
+	return r
+}
+
+// RegisterDecoder adds or replaces the decoder factory for an encoding token
+// (matched case-insensitively against Content-Encoding tokens).
+func (r *DecoderRegistry) RegisterDecoder(name string, factory DecoderFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decoders[strings.ToLower(name)] = factory
+}
+
+func (r *DecoderRegistry) lookup(name string) (DecoderFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	factory, ok := r.decoders[strings.ToLower(name)]
+	return factory, ok
 }
 
-func decompressGzip(data []byte) (*DecompressResult, error) {
-	reader, err := gzip.NewReader(bytes.NewReader(data))
+// Decompress decompresses data based on the content-encoding, buffering the
+// full result in memory. encoding may be empty, a single token, or a
+// comma-separated stack such as "br, gzip". An empty or fully-unrecognized
+// encoding is treated as uncompressed and returned as-is.
+func (r *DecoderRegistry) Decompress(data []byte, encoding string) (*DecompressResult, error) {
+	stream, err := r.DecompressStream(bytes.NewReader(data), encoding)
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Close()
+	defer stream.Close()
 
-	decompressed, err := io.ReadAll(reader)
+	decompressed, err := io.ReadAll(stream)
 	if err != nil {
 		return nil, err
 	}
@@ -54,32 +124,154 @@ func decompressGzip(data []byte) (*DecompressResult, error) {
 	}, nil
 }
 
-func decompressDeflate(data []byte) (*DecompressResult, error) {
-	reader := flate.NewReader(bytes.NewReader(data))
-	defer reader.Close()
+// DecompressStream wraps reader with the decoders named by encoding (applied
+// in reverse of the listed order, since Content-Encoding lists transformations
+// in the order they were applied when encoding) so large bodies can be read
+// incrementally instead of buffered entirely. The returned reader enforces
+// MaxDecompressedBytes and the ratio guard as the caller reads from it.
+func (r *DecoderRegistry) DecompressStream(reader io.Reader, encoding string) (io.ReadCloser, error) {
+	tokens := parseEncodingTokens(encoding)
+	if len(tokens) == 0 {
+		return io.NopCloser(reader), nil
+	}
+
+	counting := &countingReader{r: reader}
+	var current io.Reader = counting
+	var closers []io.Closer
 
-	decompressed, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, err
+	for i := len(tokens) - 1; i >= 0; i-- {
+		token := tokens[i]
+		factory, ok := r.lookup(token)
+		if !ok {
+			// Unrecognized encoding: pass the stream through untouched, matching
+			// the previous behavior of returning unknown-encoding bodies as-is.
+			continue
+		}
+
+		rc, err := factory(current)
+		if err != nil {
+			closeAll(closers)
+			return nil, fmt.Errorf("failed to initialize %s decoder: %w", token, err)
+		}
+		closers = append(closers, rc)
+		current = rc
 	}
 
-	return &DecompressResult{
-		Data:             decompressed,
-		CompressedSize:   len(data),
-		DecompressedSize: len(decompressed),
+	return &guardedReader{
+		reader:   current,
+		counting: counting,
+		closers:  closers,
+		maxBytes: r.MaxDecompressedBytes,
+		maxRatio: r.MaxRatio,
 	}, nil
 }
 
-func decompressBrotli(data []byte) (*DecompressResult, error) {
-	reader := brotli.NewReader(bytes.NewReader(data))
-	decompressed, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, err
+func parseEncodingTokens(encoding string) []string {
+	if encoding == "" {
+		return nil
 	}
 
-	return &DecompressResult{
-		Data:             decompressed,
-		CompressedSize:   len(data),
-		DecompressedSize: len(decompressed),
-	}, nil
+	var tokens []string
+	for _, part := range strings.Split(encoding, ",") {
+		if token := strings.ToLower(strings.TrimSpace(part)); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+// countingReader tallies bytes read from the underlying compressed stream so
+// guardedReader can compute a live decompression ratio.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// guardedReader wraps the fully-decoded stream, rejecting reads that exceed
+// maxBytes or, past ratioGuardThreshold bytes of output, a decompressed/
+// compressed ratio of maxRatio - the zip-bomb guard.
+type guardedReader struct {
+	reader   io.Reader
+	counting *countingReader
+	closers  []io.Closer
+
+	bytesOut int64
+	maxBytes int64
+	maxRatio float64
+}
+
+func (g *guardedReader) Read(p []byte) (int, error) {
+	n, err := g.reader.Read(p)
+	if n > 0 {
+		g.bytesOut += int64(n)
+
+		if g.maxBytes > 0 && g.bytesOut > g.maxBytes {
+			return n, fmt.Errorf("decompression exceeded max decompressed size of %d bytes", g.maxBytes)
+		}
+
+		if g.maxRatio > 0 && g.bytesOut > ratioGuardThreshold && g.counting.n > 0 {
+			if ratio := float64(g.bytesOut) / float64(g.counting.n); ratio > g.maxRatio {
+				return n, fmt.Errorf("decompression ratio %.0fx exceeded guard of %.0fx (possible zip bomb)", ratio, g.maxRatio)
+			}
+		}
+	}
+	return n, err
+}
+
+func (g *guardedReader) Close() error {
+	// Close in reverse order of opening: the last decoder opened (the one
+	// wrapping all the others) first.
+	var firstErr error
+	for i := len(g.closers) - 1; i >= 0; i-- {
+		if err := g.closers[i].Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// zstdReadCloser adapts *zstd.Decoder's no-return-value Close to io.ReadCloser.
+type zstdReadCloser struct {
+	dec *zstd.Decoder
+}
+
+func (z *zstdReadCloser) Read(p []byte) (int, error) {
+	return z.dec.Read(p)
+}
+
+func (z *zstdReadCloser) Close() error {
+	z.dec.Close()
+	return nil
+}
+
+// defaultRegistry is the package-level registry backing the Decompress,
+// DecompressStream and RegisterDecoder package functions.
+var defaultRegistry = NewDecoderRegistry()
+
+// Decompress decompresses data based on the content-encoding, using the
+// default decoder registry.
+func Decompress(data []byte, encoding string) (*DecompressResult, error) {
+	return defaultRegistry.Decompress(data, encoding)
+}
+
+// DecompressStream streams decompression using the default decoder registry.
+func DecompressStream(reader io.Reader, encoding string) (io.ReadCloser, error) {
+	return defaultRegistry.DecompressStream(reader, encoding)
+}
+
+// RegisterDecoder registers a custom decoder on the default decoder registry.
+func RegisterDecoder(name string, factory DecoderFactory) {
+	defaultRegistry.RegisterDecoder(name, factory)
 }
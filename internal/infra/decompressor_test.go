@@ -0,0 +1,108 @@
+package infra
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressStreamGzipRoundTrip(t *testing.T) {
+	want := []byte(strings.Repeat("hello world ", 100))
+	compressed := gzipBytes(t, want)
+
+	stream, err := DecompressStream(bytes.NewReader(compressed), "gzip")
+	if err != nil {
+		t.Fatalf("DecompressStream: %v", err)
+	}
+	defer stream.Close()
+
+	got, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecompressStreamUnrecognizedEncodingPassesThrough(t *testing.T) {
+	want := []byte("not actually compressed")
+
+	stream, err := DecompressStream(bytes.NewReader(want), "identity")
+	if err != nil {
+		t.Fatalf("DecompressStream: %v", err)
+	}
+	defer stream.Close()
+
+	got, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestGuardedReaderRejectsExcessiveRatio(t *testing.T) {
+	// A long run of zeros compresses to a tiny gzip stream, giving an easy
+	// way to exceed a deliberately low ratio guard without needing a
+	// realistic zip bomb's full size.
+	huge := bytes.Repeat([]byte{0}, 10*1024*1024)
+	compressed := gzipBytes(t, huge)
+
+	registry := NewDecoderRegistry()
+	registry.MaxDecompressedBytes = 0
+	registry.MaxRatio = 10
+
+	stream, err := registry.DecompressStream(bytes.NewReader(compressed), "gzip")
+	if err != nil {
+		t.Fatalf("DecompressStream: %v", err)
+	}
+	defer stream.Close()
+
+	_, err = io.ReadAll(stream)
+	if err == nil {
+		t.Fatal("expected ratio guard to reject the stream, got nil error")
+	}
+	if !strings.Contains(err.Error(), "ratio") {
+		t.Fatalf("expected a ratio-guard error, got: %v", err)
+	}
+}
+
+func TestGuardedReaderRejectsExcessiveSize(t *testing.T) {
+	want := []byte(strings.Repeat("x", 1024))
+	compressed := gzipBytes(t, want)
+
+	registry := NewDecoderRegistry()
+	registry.MaxDecompressedBytes = 100
+	registry.MaxRatio = 0
+
+	stream, err := registry.DecompressStream(bytes.NewReader(compressed), "gzip")
+	if err != nil {
+		t.Fatalf("DecompressStream: %v", err)
+	}
+	defer stream.Close()
+
+	_, err = io.ReadAll(stream)
+	if err == nil {
+		t.Fatal("expected max-size guard to reject the stream, got nil error")
+	}
+	if !strings.Contains(err.Error(), "max decompressed size") {
+		t.Fatalf("expected a max-size error, got: %v", err)
+	}
+}
@@ -2,44 +2,411 @@
 package infra
 
 import (
+	"bytes"
+	"container/list"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"sync"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
 // DNSResult contains DNS resolution results and timing information.
 type DNSResult struct {
 	IPs        []net.IP
 	DurationMs uint64
+
+	// Upstream identifies which resolution path produced IPs: "literal" for
+	// an address that needed no lookup, "system", or "doh:<endpoint>" /
+	// "dot:<server>" naming the fallback upstream that answered.
+	Upstream string
+	// TTL is the (clamped) time the answer is cached for.
+	TTL time.Duration
+	// FromCache reports whether this result was served from the resolver's
+	// cache rather than a live lookup.
+	FromCache bool
+}
+
+// Default TTL bounds applied to cached answers: a floor so a misconfigured
+// authoritative server with a near-zero TTL can't force a lookup per
+// request, and a ceiling so a very large TTL doesn't pin a stale address
+// for hours after it changes.
+const (
+	DefaultMinTTL      = 30 * time.Second
+	DefaultMaxTTL      = 1 * time.Hour
+	DefaultNegativeTTL = 30 * time.Second
+	DefaultMaxEntries  = 10000
+)
+
+// DefaultDoHEndpoints and DefaultDoTServers are tried, in order, after the
+// system resolver fails - e.g. when the network blocks outbound port 53 but
+// allows 443.
+var (
+	DefaultDoHEndpoints = []string{"https://cloudflare-dns.com/dns-query"}
+	DefaultDoTServers   = []string{"1.1.1.1:853"}
+)
+
+// ResolverOptions configures a Resolver's cache bounds and fallback upstreams.
+// The zero value is not ready to use; construct via NewResolver, which fills
+// in defaults for any field left unset.
+type ResolverOptions struct {
+	MinTTL      time.Duration // floor applied to a cached answer's TTL
+	MaxTTL      time.Duration // ceiling applied to a cached answer's TTL
+	NegativeTTL time.Duration // how long an NXDOMAIN is cached
+	MaxEntries  int           // LRU eviction threshold
+
+	// DoHEndpoints and DoTServers are tried in order after the system
+	// resolver fails, and after each other (DoH before DoT).
+	DoHEndpoints []string
+	DoTServers   []string
+}
+
+// withDefaults returns opts with every zero-valued field replaced by its
+// package default.
+func (o ResolverOptions) withDefaults() ResolverOptions {
+	if o.MinTTL <= 0 {
+		o.MinTTL = DefaultMinTTL
+	}
+	if o.MaxTTL <= 0 {
+		o.MaxTTL = DefaultMaxTTL
+	}
+	if o.NegativeTTL <= 0 {
+		o.NegativeTTL = DefaultNegativeTTL
+	}
+	if o.MaxEntries <= 0 {
+		o.MaxEntries = DefaultMaxEntries
+	}
+	if len(o.DoHEndpoints) == 0 {
+		o.DoHEndpoints = DefaultDoHEndpoints
+	}
+	if len(o.DoTServers) == 0 {
+		o.DoTServers = DefaultDoTServers
+	}
+	return o
+}
+
+// resolverCacheEntry is the LRU payload for one hostname, either a positive
+// answer (ips set) or a negative one (negative true, cached NXDOMAIN).
+type resolverCacheEntry struct {
+	host      string
+	ips       []net.IP
+	negative  bool
+	upstream  string
+	ttl       time.Duration
+	expiresAt time.Time
+}
+
+// Resolver resolves hostnames to IP addresses, caching both positive and
+// negative (NXDOMAIN) answers in an LRU bounded by TTL. The system resolver
+// is tried first; DNS-over-HTTPS and then DNS-over-TLS upstreams are tried,
+// in the configured order, before giving up.
+type Resolver struct {
+	opts ResolverOptions
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // host -> element in lru
+	lru     *list.List               // front = most recently used
+}
+
+// NewResolver creates a Resolver, filling in any unset ResolverOptions with
+// package defaults.
+func NewResolver(opts ResolverOptions) *Resolver {
+	return &Resolver{
+		opts:    opts.withDefaults(),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
 }
 
-// ResolveDNS resolves a hostname to IP addresses with timing.
+// DefaultResolver is the package-level Resolver backing ResolveDNS.
+var DefaultResolver = NewResolver(ResolverOptions{})
+
+type resolverContextKey struct{}
+
+// WithResolver returns a context that ResolveDNS will use r for, instead of
+// DefaultResolver - letting a caller A/B compare resolution paths (e.g. a
+// different DoH endpoint) for the same host without a global config change.
+func WithResolver(ctx context.Context, r *Resolver) context.Context {
+	return context.WithValue(ctx, resolverContextKey{}, r)
+}
+
+// resolverFromContext returns the Resolver bound to ctx via WithResolver, or
+// DefaultResolver if none was bound.
+func resolverFromContext(ctx context.Context) *Resolver {
+	if r, ok := ctx.Value(resolverContextKey{}).(*Resolver); ok && r != nil {
+		return r
+	}
+	return DefaultResolver
+}
+
+// ErrNXDomain is returned (possibly wrapped) when a host has no records,
+// including on a cached negative answer.
+var ErrNXDomain = errors.New("no such host")
+
+// ResolveDNS resolves a hostname to IP addresses with timing, using the
+// Resolver bound to ctx via WithResolver, or DefaultResolver if none is bound.
 func ResolveDNS(ctx context.Context, host string) (*DNSResult, error) {
+	return resolverFromContext(ctx).Resolve(ctx, host)
+}
+
+// Resolve resolves host to IP addresses, serving from cache (positive or
+// negative) when a non-expired entry exists, and falling back through the
+// system resolver, then DoH, then DoT otherwise.
+func (r *Resolver) Resolve(ctx context.Context, host string) (*DNSResult, error) {
 	start := time.Now()
 
-	// Check if already an IP address
 	if ip := net.ParseIP(host); ip != nil {
+		return &DNSResult{IPs: []net.IP{ip}, Upstream: "literal"}, nil
+	}
+
+	if entry, ok := r.lookup(host); ok {
+		if entry.negative {
+			return nil, fmt.Errorf("%w: %s (cached)", ErrNXDomain, host)
+		}
 		return &DNSResult{
-			IPs:        []net.IP{ip},
+			IPs:        entry.ips,
 			DurationMs: 0,
+			Upstream:   entry.upstream,
+			TTL:        entry.ttl,
+			FromCache:  true,
 		}, nil
 	}
 
-	// Perform DNS lookup
-	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	ips, upstream, ttl, err := r.resolveWithFallback(ctx, host)
 	if err != nil {
+		if errors.Is(err, ErrNXDomain) {
+			r.store(host, resolverCacheEntry{negative: true}, r.opts.NegativeTTL)
+		}
 		return nil, err
 	}
 
-	if len(ips) == 0 {
-		return nil, &net.DNSError{
-			Err:  "no addresses found",
-			Name: host,
-		}
-	}
+	ttl = clampTTL(ttl, r.opts.MinTTL, r.opts.MaxTTL)
+	r.store(host, resolverCacheEntry{ips: ips, upstream: upstream, ttl: ttl}, ttl)
 
 	return &DNSResult{
 		IPs:        ips,
 		DurationMs: uint64(time.Since(start).Milliseconds()),
+		Upstream:   upstream,
+		TTL:        ttl,
 	}, nil
 }
+
+// resolveWithFallback tries the system resolver, then each configured DoH
+// endpoint, then each configured DoT server, returning the first to produce
+// at least one address. It returns the minimum TTL seen across every answer
+// record, or 0 when the upstream doesn't carry TTL information (the system
+// resolver).
+func (r *Resolver) resolveWithFallback(ctx context.Context, host string) ([]net.IP, string, time.Duration, error) {
+	systemIPs, systemErr := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if systemErr == nil && len(systemIPs) > 0 {
+		return systemIPs, "system", 0, nil
+	}
+
+	var dohErr, dotErr error
+	for _, endpoint := range r.opts.DoHEndpoints {
+		ips, ttl, err := resolveDoH(ctx, endpoint, host)
+		if err == nil && len(ips) > 0 {
+			return ips, "doh:" + endpoint, ttl, nil
+		}
+		if err != nil {
+			dohErr = err
+		}
+	}
+
+	for _, server := range r.opts.DoTServers {
+		ips, ttl, err := resolveDoT(ctx, server, host)
+		if err == nil && len(ips) > 0 {
+			return ips, "dot:" + server, ttl, nil
+		}
+		if err != nil {
+			dotErr = err
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(systemErr, &dnsErr) && dnsErr.IsNotFound {
+		return nil, "", 0, fmt.Errorf("%w: %s", ErrNXDomain, host)
+	}
+
+	return nil, "", 0, fmt.Errorf("no addresses found for %s (system: %v, doh: %v, dot: %v)", host, systemErr, dohErr, dotErr)
+}
+
+// resolveDoH resolves host's A and AAAA records over DNS-over-HTTPS (RFC
+// 8484), POSTing the wire-format query to endpoint. It returns the minimum
+// TTL across both answers.
+func resolveDoH(ctx context.Context, endpoint, host string) ([]net.IP, time.Duration, error) {
+	var ips []net.IP
+	minTTL := time.Duration(0)
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(host), qtype)
+
+		wire, err := msg.Pack()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(wire))
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set("Content-Type", "application/dns-message")
+		req.Header.Set("Accept", "application/dns-message")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		reply := new(dns.Msg)
+		if err := reply.Unpack(body); err != nil {
+			return nil, 0, err
+		}
+		if reply.Rcode == dns.RcodeNameError {
+			return nil, 0, fmt.Errorf("%w: %s", ErrNXDomain, host)
+		}
+
+		qips, qttl := answersToIPs(reply.Answer)
+		ips = append(ips, qips...)
+		minTTL = minDuration(minTTL, qttl)
+	}
+	return ips, minTTL, nil
+}
+
+// resolveDoT resolves host's A and AAAA records over DNS-over-TLS, using
+// miekg/dns's "tcp-tls" network to handle the TLS handshake and framing. It
+// returns the minimum TTL across both answers.
+func resolveDoT(ctx context.Context, server, host string) ([]net.IP, time.Duration, error) {
+	client := &dns.Client{Net: "tcp-tls", Timeout: 5 * time.Second}
+
+	var ips []net.IP
+	minTTL := time.Duration(0)
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(host), qtype)
+
+		reply, _, err := client.ExchangeContext(ctx, msg, server)
+		if err != nil {
+			return nil, 0, err
+		}
+		if reply.Rcode == dns.RcodeNameError {
+			return nil, 0, fmt.Errorf("%w: %s", ErrNXDomain, host)
+		}
+
+		qips, qttl := answersToIPs(reply.Answer)
+		ips = append(ips, qips...)
+		minTTL = minDuration(minTTL, qttl)
+	}
+	return ips, minTTL, nil
+}
+
+// answersToIPs extracts A/AAAA addresses from a DNS answer section, along
+// with the minimum TTL (in seconds, per the RR header) across them.
+func answersToIPs(answers []dns.RR) ([]net.IP, time.Duration) {
+	var ips []net.IP
+	minTTL := time.Duration(0)
+
+	for _, rr := range answers {
+		var ttl time.Duration
+		switch record := rr.(type) {
+		case *dns.A:
+			ips = append(ips, record.A)
+			ttl = time.Duration(record.Hdr.Ttl) * time.Second
+		case *dns.AAAA:
+			ips = append(ips, record.AAAA)
+			ttl = time.Duration(record.Hdr.Ttl) * time.Second
+		default:
+			continue
+		}
+		minTTL = minDuration(minTTL, ttl)
+	}
+	return ips, minTTL
+}
+
+// minDuration returns the smaller of a and b, treating a zero value as "not
+// yet set" rather than "zero" so the first real TTL seen isn't discarded.
+func minDuration(a, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// clampTTL bounds ttl to [floor, ceiling], substituting floor when ttl is
+// unknown (zero or negative, e.g. the system resolver doesn't report one).
+func clampTTL(ttl, floor, ceiling time.Duration) time.Duration {
+	if ttl <= 0 {
+		return floor
+	}
+	if ttl < floor {
+		return floor
+	}
+	if ttl > ceiling {
+		return ceiling
+	}
+	return ttl
+}
+
+func (r *Resolver) lookup(host string) (resolverCacheEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[host]
+	if !ok {
+		return resolverCacheEntry{}, false
+	}
+
+	entry := elem.Value.(resolverCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		r.lru.Remove(elem)
+		delete(r.entries, host)
+		return resolverCacheEntry{}, false
+	}
+
+	r.lru.MoveToFront(elem)
+	return entry, true
+}
+
+func (r *Resolver) store(host string, entry resolverCacheEntry, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.host = host
+	entry.expiresAt = time.Now().Add(ttl)
+
+	if elem, ok := r.entries[host]; ok {
+		elem.Value = entry
+		r.lru.MoveToFront(elem)
+		return
+	}
+
+	elem := r.lru.PushFront(entry)
+	r.entries[host] = elem
+
+	for r.lru.Len() > r.opts.MaxEntries {
+		oldest := r.lru.Back()
+		if oldest == nil {
+			break
+		}
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(resolverCacheEntry).host)
+	}
+}
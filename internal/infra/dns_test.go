@@ -0,0 +1,132 @@
+package infra
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestResolveLiteralIPSkipsCache(t *testing.T) {
+	r := NewResolver(ResolverOptions{})
+
+	result, err := r.Resolve(context.Background(), "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if result.Upstream != "literal" {
+		t.Fatalf("expected upstream %q, got %q", "literal", result.Upstream)
+	}
+	if result.FromCache {
+		t.Fatal("a literal IP should never be reported as served from cache")
+	}
+	if len(result.IPs) != 1 || !result.IPs[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("unexpected IPs: %v", result.IPs)
+	}
+}
+
+func TestResolverCacheHitReportsProvenance(t *testing.T) {
+	r := NewResolver(ResolverOptions{})
+	r.store("example.test", resolverCacheEntry{
+		ips:      []net.IP{net.ParseIP("10.0.0.1")},
+		upstream: "system",
+	}, time.Minute)
+
+	result, err := r.Resolve(context.Background(), "example.test")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !result.FromCache {
+		t.Fatal("expected FromCache to be true for a cached entry")
+	}
+	if result.Upstream != "system" {
+		t.Fatalf("expected upstream %q, got %q", "system", result.Upstream)
+	}
+}
+
+func TestResolverNegativeCacheReturnsNXDomain(t *testing.T) {
+	r := NewResolver(ResolverOptions{})
+	r.store("missing.test", resolverCacheEntry{negative: true}, time.Minute)
+
+	_, err := r.Resolve(context.Background(), "missing.test")
+	if err == nil {
+		t.Fatal("expected an error for a negatively-cached host")
+	}
+}
+
+func TestResolverExpiredEntryIsEvictedOnLookup(t *testing.T) {
+	r := NewResolver(ResolverOptions{})
+	r.store("stale.test", resolverCacheEntry{ips: []net.IP{net.ParseIP("10.0.0.2")}}, -time.Second)
+
+	if _, ok := r.lookup("stale.test"); ok {
+		t.Fatal("expected an already-expired entry to be evicted on lookup")
+	}
+}
+
+func TestResolverLRUEvictsOldestBeyondMaxEntries(t *testing.T) {
+	r := NewResolver(ResolverOptions{MaxEntries: 2})
+
+	r.store("a.test", resolverCacheEntry{ips: []net.IP{net.ParseIP("10.0.0.1")}}, time.Minute)
+	r.store("b.test", resolverCacheEntry{ips: []net.IP{net.ParseIP("10.0.0.2")}}, time.Minute)
+	r.store("c.test", resolverCacheEntry{ips: []net.IP{net.ParseIP("10.0.0.3")}}, time.Minute)
+
+	if _, ok := r.lookup("a.test"); ok {
+		t.Fatal("expected the oldest entry to be evicted once MaxEntries was exceeded")
+	}
+	if _, ok := r.lookup("b.test"); !ok {
+		t.Fatal("expected b.test to still be cached")
+	}
+	if _, ok := r.lookup("c.test"); !ok {
+		t.Fatal("expected c.test to still be cached")
+	}
+}
+
+func TestClampTTL(t *testing.T) {
+	floor, ceiling := 30*time.Second, time.Hour
+
+	if got := clampTTL(0, floor, ceiling); got != floor {
+		t.Fatalf("unknown TTL: got %v, want %v", got, floor)
+	}
+	if got := clampTTL(5*time.Second, floor, ceiling); got != floor {
+		t.Fatalf("below floor: got %v, want %v", got, floor)
+	}
+	if got := clampTTL(2*time.Hour, floor, ceiling); got != ceiling {
+		t.Fatalf("above ceiling: got %v, want %v", got, ceiling)
+	}
+	if got := clampTTL(5*time.Minute, floor, ceiling); got != 5*time.Minute {
+		t.Fatalf("within bounds: got %v, want %v", got, 5*time.Minute)
+	}
+}
+
+func TestMinDuration(t *testing.T) {
+	if got := minDuration(0, 5*time.Second); got != 5*time.Second {
+		t.Fatalf("zero a: got %v, want %v", got, 5*time.Second)
+	}
+	if got := minDuration(5*time.Second, 0); got != 5*time.Second {
+		t.Fatalf("zero b: got %v, want %v", got, 5*time.Second)
+	}
+	if got := minDuration(2*time.Second, 5*time.Second); got != 2*time.Second {
+		t.Fatalf("both set: got %v, want %v", got, 2*time.Second)
+	}
+}
+
+func TestWithResolverOverridesDefaultPerRequest(t *testing.T) {
+	override := NewResolver(ResolverOptions{})
+	override.store("override.test", resolverCacheEntry{
+		ips:      []net.IP{net.ParseIP("10.0.0.9")},
+		upstream: "system",
+	}, time.Minute)
+
+	ctx := WithResolver(context.Background(), override)
+	result, err := ResolveDNS(ctx, "override.test")
+	if err != nil {
+		t.Fatalf("ResolveDNS: %v", err)
+	}
+	if !result.FromCache {
+		t.Fatal("expected the override resolver's cached entry to be used")
+	}
+
+	if _, ok := DefaultResolver.lookup("override.test"); ok {
+		t.Fatal("the override resolver's cache must not leak into DefaultResolver")
+	}
+}
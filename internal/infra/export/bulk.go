@@ -0,0 +1,83 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// largeBodyThreshold is the response body size above which BulkHAR writes
+// the body to its own file in the zip instead of inlining it into the HAR.
+const largeBodyThreshold = 256 * 1024 // 256 KiB
+
+// BulkHAR packages many exchanges into a single HAR 1.2 log plus, for any
+// response whose body exceeds largeBodyThreshold, a separate file in the
+// same zip - so a bug report doesn't balloon one multi-megabyte JSON
+// document. capturedAt must be the same length as exchanges and gives each
+// entry's startedDateTime.
+func BulkHAR(exchanges []Exchange, capturedAt []time.Time) ([]byte, error) {
+	if len(capturedAt) != len(exchanges) {
+		return nil, fmt.Errorf("capturedAt must have one entry per exchange")
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	entries := make([]harEntry, 0, len(exchanges))
+	for i, ex := range exchanges {
+		exporter := HARExporter{StartedDateTime: capturedAt[i].Format(time.RFC3339)}
+		entry := exporter.toEntry(ex)
+
+		if len(ex.ResponseBody) > largeBodyThreshold {
+			bodyFile := fmt.Sprintf("bodies/%d%s", i, bodyExtension(ex))
+			w, err := zw.Create(bodyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add %s to export zip: %w", bodyFile, err)
+			}
+			if _, err := w.Write(ex.ResponseBody); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", bodyFile, err)
+			}
+
+			// Point the HAR entry at the body file instead of inlining it.
+			entry.Response.Content.Text = ""
+			entry.Response.Content.Encoding = ""
+			entry.Response.Content.Comment = bodyFile
+		}
+
+		entries = append(entries, entry)
+	}
+
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator,
+		Entries: entries,
+	}}
+
+	harBytes, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal HAR: %w", err)
+	}
+
+	harWriter, err := zw.Create("requests.har")
+	if err != nil {
+		return nil, fmt.Errorf("failed to add requests.har to export zip: %w", err)
+	}
+	if _, err := harWriter.Write(harBytes); err != nil {
+		return nil, fmt.Errorf("failed to write requests.har: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize export zip: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func bodyExtension(ex Exchange) string {
+	if ex.IsBinary {
+		return ".bin"
+	}
+	return ".txt"
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// CurlExporter renders an Exchange's request side as a replayable curl
+// command. Non-text bodies are written with --data-binary against a
+// companion body file the caller is expected to save alongside the command.
+type CurlExporter struct{}
+
+// Export implements Exporter. The response side of ex is ignored - curl
+// reproduces a request, not a captured response.
+func (CurlExporter) Export(ex Exchange) ([]byte, string, string, error) {
+	var b strings.Builder
+
+	b.WriteString("curl")
+	if ex.Method != "" && ex.Method != "GET" {
+		fmt.Fprintf(&b, " -X %s", shellQuote(ex.Method))
+	}
+	fmt.Fprintf(&b, " %s", shellQuote(ex.URL))
+
+	for _, key := range sortedHeaderKeys(ex.RequestHeaders) {
+		fmt.Fprintf(&b, " \\\n  -H %s", shellQuote(fmt.Sprintf("%s: %s", key, ex.RequestHeaders[key])))
+	}
+
+	if len(ex.RequestBody) > 0 {
+		if utf8.Valid(ex.RequestBody) {
+			b.WriteString(" \\\n  --data-raw ")
+			b.WriteString(shellQuote(string(ex.RequestBody)))
+		} else {
+			b.WriteString(" \\\n  --data-binary @request-body.bin")
+		}
+	}
+
+	return []byte(b.String()), "request.sh", "text/x-shellscript", nil
+}
+
+// shellQuote wraps s in single quotes, escaping any single quote it
+// contains so the result is safe to paste into a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,83 @@
+// Package export converts a captured HTTP request/response exchange into
+// portable, replayable artifacts: a curl command, a JetBrains/VS Code .http
+// file, or a HAR entry.
+package export
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Timing mirrors the DNS/connect/TLS/wait/receive breakdown HAR expects, all
+// in milliseconds. A phase that wasn't measured should be left at -1, HAR's
+// convention for "not applicable".
+type Timing struct {
+	DNS     int64
+	Connect int64
+	SSL     int64
+	Wait    int64
+	Receive int64
+}
+
+// Exchange is the neutral, export-package-owned view of one captured
+// request/response pair. Callers translate their own request/response types
+// into this before calling an Exporter.
+type Exchange struct {
+	Method         string
+	URL            string
+	HTTPVersion    string // e.g. "HTTP/1.1"; defaults to "HTTP/1.1" if empty
+	RequestHeaders map[string]string
+	RequestBody    []byte
+
+	Status          int
+	StatusText      string
+	ResponseHeaders map[string]string
+	ResponseBody    []byte
+	IsBinary        bool
+
+	Timing Timing
+}
+
+// Exporter converts an Exchange into a named artifact: the encoded bytes, a
+// suggested file name, and its MIME type.
+type Exporter interface {
+	Export(ex Exchange) (data []byte, filename string, mimeType string, err error)
+}
+
+// ForFormat looks up the built-in Exporter for a format token ("curl",
+// "http" or "har"), as used by the format query parameter.
+func ForFormat(format string) (Exporter, error) {
+	switch strings.ToLower(format) {
+	case "curl":
+		return CurlExporter{}, nil
+	case "http":
+		return HTTPFileExporter{}, nil
+	case "har":
+		return HARExporter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// sortedHeaderKeys returns a header map's keys in a stable order, so exported
+// artifacts are deterministic and diff-friendly.
+func sortedHeaderKeys(headers map[string]string) []string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// bodyAsText returns the response body as text, or base64-encoded when the
+// Exchange's own IsBinary classification marks it binary; the bool reports
+// whether the returned string is plain text.
+func bodyAsText(ex Exchange) (string, bool) {
+	if ex.IsBinary {
+		return base64.StdEncoding.EncodeToString(ex.ResponseBody), false
+	}
+	return string(ex.ResponseBody), true
+}
@@ -0,0 +1,200 @@
+package export
+
+import (
+	"encoding/json"
+)
+
+// harCreator identifies Project Tommie as the HAR 1.2 log's "creator", per spec.
+var harCreator = harNameVersion{Name: "tommie", Version: "1.0"}
+
+type harNameVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harLog struct {
+	Version string         `json:"version"`
+	Creator harNameVersion `json:"creator"`
+	Entries []harEntry     `json:"entries"`
+}
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Headers     []harHeader  `json:"headers"`
+	QueryString []harHeader  `json:"queryString"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int          `json:"bodySize"`
+	PostData    *harPostData `json:"postData,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	// Comment points at a companion file in a bulk export's zip when the body
+	// was too large to inline; empty for a standalone single-entry export.
+	Comment string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	Content     harContent  `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type harTimings struct {
+	DNS     int64 `json:"dns"`
+	Connect int64 `json:"connect"`
+	SSL     int64 `json:"ssl"`
+	Send    int64 `json:"send"`
+	Wait    int64 `json:"wait"`
+	Receive int64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            int64       `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+// HARExporter renders an Exchange as a single HAR 1.2 log entry, suitable for
+// embedding into log.entries[] or wrapping in its own top-level document.
+type HARExporter struct {
+	// StartedDateTime is stamped onto the entry; callers pass this in (rather
+	// than the exporter calling time.Now()) so a bulk export can give every
+	// entry the time it was actually captured.
+	StartedDateTime string
+}
+
+// Export implements Exporter, producing a standalone HAR document containing
+// exactly this one entry.
+func (e HARExporter) Export(ex Exchange) ([]byte, string, string, error) {
+	entry := e.toEntry(ex)
+
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator,
+		Entries: []harEntry{entry},
+	}}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return data, "request.har", "application/json", nil
+}
+
+// toEntry builds just the harEntry for ex, so bulk export can assemble many
+// entries into one log without re-marshaling each as its own document.
+func (e HARExporter) toEntry(ex Exchange) harEntry {
+	version := ex.HTTPVersion
+	if version == "" {
+		version = "HTTP/1.1"
+	}
+
+	request := harRequest{
+		Method:      ex.Method,
+		URL:         ex.URL,
+		HTTPVersion: version,
+		Headers:     toHARHeaders(ex.RequestHeaders),
+		QueryString: []harHeader{},
+		BodySize:    len(ex.RequestBody),
+	}
+	if len(ex.RequestBody) > 0 {
+		request.PostData = &harPostData{
+			MimeType: ex.RequestHeaders["content-type"],
+			Text:     string(ex.RequestBody),
+		}
+	}
+
+	content := harContent{
+		Size:     len(ex.ResponseBody),
+		MimeType: ex.ResponseHeaders["content-type"],
+	}
+	if text, plain := bodyAsText(ex); plain {
+		content.Text = text
+	} else {
+		content.Text = text
+		content.Encoding = "base64"
+	}
+
+	response := harResponse{
+		Status:      ex.Status,
+		StatusText:  ex.StatusText,
+		HTTPVersion: version,
+		Headers:     toHARHeaders(ex.ResponseHeaders),
+		Content:     content,
+		BodySize:    len(ex.ResponseBody),
+	}
+
+	timings := harTimings{
+		DNS:     ex.Timing.DNS,
+		Connect: ex.Timing.Connect,
+		SSL:     ex.Timing.SSL,
+		Send:    0,
+		Wait:    ex.Timing.Wait,
+		Receive: ex.Timing.Receive,
+	}
+
+	return harEntry{
+		StartedDateTime: e.StartedDateTime,
+		Time:            sumTimings(timings),
+		Request:         request,
+		Response:        response,
+		Timings:         timings,
+	}
+}
+
+func toHARHeaders(headers map[string]string) []harHeader {
+	out := make([]harHeader, 0, len(headers))
+	for _, key := range sortedHeaderKeys(headers) {
+		out = append(out, harHeader{Name: key, Value: headers[key]})
+	}
+	return out
+}
+
+func sumTimings(t harTimings) int64 {
+	total := t.Send
+	if t.Wait > 0 {
+		total += t.Wait
+	}
+	if t.Receive > 0 {
+		total += t.Receive
+	}
+	if t.DNS > 0 {
+		total += t.DNS
+	}
+	if t.Connect > 0 {
+		total += t.Connect
+	}
+	if t.SSL > 0 {
+		total += t.SSL
+	}
+	return total
+}
@@ -0,0 +1,33 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HTTPFileExporter renders an Exchange's request side as a JetBrains/VS Code
+// REST Client ".http" block.
+type HTTPFileExporter struct{}
+
+// Export implements Exporter.
+func (HTTPFileExporter) Export(ex Exchange) ([]byte, string, string, error) {
+	version := ex.HTTPVersion
+	if version == "" {
+		version = "HTTP/1.1"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\n", ex.Method, ex.URL, version)
+
+	for _, key := range sortedHeaderKeys(ex.RequestHeaders) {
+		fmt.Fprintf(&b, "%s: %s\n", key, ex.RequestHeaders[key])
+	}
+
+	if len(ex.RequestBody) > 0 {
+		b.WriteString("\n")
+		b.Write(ex.RequestBody)
+		b.WriteString("\n")
+	}
+
+	return []byte(b.String()), "request.http", "text/plain", nil
+}
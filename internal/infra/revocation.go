@@ -0,0 +1,288 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// DefaultRevocationTimeout bounds how long CheckRevocation will wait on a
+// single OCSP/CRL network round trip when the caller's context carries no
+// deadline of its own, so a slow or unresponsive responder can't hang the
+// request it's checking on indefinitely.
+const DefaultRevocationTimeout = 5 * time.Second
+
+// Revocation status values surfaced per certificate in the chain.
+const (
+	RevocationGood    = "good"
+	RevocationRevoked = "revoked"
+	RevocationUnknown = "unknown"
+)
+
+// CertRevocation is the revocation result for a single certificate in a chain.
+type CertRevocation struct {
+	Status  string `json:"status"` // "good", "revoked" or "unknown"
+	Reason  *int   `json:"reason,omitempty"`
+	Stapled bool   `json:"stapled"`
+	Source  string `json:"source"` // "ocsp-staple", "ocsp", "crl" or "" when unknown
+}
+
+// RevocationStatus reports revocation results for an entire chain, in leaf-first order.
+type RevocationStatus struct {
+	Entries []CertRevocation `json:"entries"`
+}
+
+// revocationCacheEntry caches a revocation lookup by issuer+serial until the
+// responder's nextUpdate, so repeated requests to the same host don't refetch.
+type revocationCacheEntry struct {
+	result    CertRevocation
+	expiresAt time.Time
+}
+
+var (
+	revocationCacheMu sync.Mutex
+	revocationCache   = make(map[string]revocationCacheEntry)
+	revocationStores  int
+)
+
+// revocationSweepInterval is how many storeRevocationCache calls happen
+// between opportunistic sweeps of expired entries, bounding the cache's
+// growth for a long-running desktop process instead of only reaping an
+// entry when it happens to be looked up again.
+const revocationSweepInterval = 64
+
+// CheckRevocation checks the revocation status of every certificate in chain
+// (leaf first, as returned by tls.ConnectionState.PeerCertificates) against a
+// stapled OCSP response if present, falling back to a live OCSP request and
+// then a CRL fetch. Results are cached by issuer+serial using the responder's
+// nextUpdate as TTL.
+//
+// ctx bounds the OCSP/CRL network round trips; if it carries no deadline,
+// DefaultRevocationTimeout is applied so a slow responder can't hang the
+// caller (normally the in-flight proxy request this chain belongs to)
+// indefinitely.
+func CheckRevocation(ctx context.Context, chain []*x509.Certificate, ocspResponse []byte) RevocationStatus {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRevocationTimeout)
+		defer cancel()
+	}
+
+	var status RevocationStatus
+
+	for i, cert := range chain {
+		if i+1 >= len(chain) {
+			// No issuer certificate available to verify against (e.g. a root).
+			status.Entries = append(status.Entries, CertRevocation{Status: RevocationUnknown})
+			continue
+		}
+		issuer := chain[i+1]
+
+		cacheKey := revocationCacheKey(issuer, cert)
+		if cached, ok := lookupRevocationCache(cacheKey); ok {
+			status.Entries = append(status.Entries, cached)
+			continue
+		}
+
+		result, ttl := checkCertRevocation(ctx, cert, issuer, i, ocspResponse)
+		status.Entries = append(status.Entries, result)
+
+		if result.Status != RevocationUnknown {
+			storeRevocationCache(cacheKey, result, ttl)
+		}
+	}
+
+	return status
+}
+
+// defaultRevocationTTL is used when a response carries no nextUpdate or its
+// nextUpdate has already passed. A nextUpdate in the future is used as is,
+// however short the resulting TTL.
+const defaultRevocationTTL = 1 * time.Hour
+
+// checkCertRevocation resolves one certificate's status: stapled OCSP first
+// (only meaningful for the leaf, index 0), then a live OCSP request, then CRL.
+// It returns the result alongside how long it should be cached for, derived
+// from the response's nextUpdate when available.
+func checkCertRevocation(ctx context.Context, cert, issuer *x509.Certificate, index int, stapled []byte) (CertRevocation, time.Duration) {
+	if index == 0 && len(stapled) > 0 {
+		if resp, err := ocsp.ParseResponse(stapled, issuer); err == nil {
+			return ocspResponseToRevocation(resp, true, "ocsp-staple"), ttlFromNextUpdate(resp.NextUpdate)
+		}
+	}
+
+	if resp, ok := fetchOCSP(ctx, cert, issuer); ok {
+		return ocspResponseToRevocation(resp, false, "ocsp"), ttlFromNextUpdate(resp.NextUpdate)
+	}
+
+	if result, nextUpdate, ok := fetchCRL(ctx, cert, issuer); ok {
+		return result, ttlFromNextUpdate(nextUpdate)
+	}
+
+	return CertRevocation{Status: RevocationUnknown}, defaultRevocationTTL
+}
+
+// ttlFromNextUpdate derives a cache TTL from a response's nextUpdate field,
+// falling back to defaultRevocationTTL when it's absent or already past.
+func ttlFromNextUpdate(nextUpdate time.Time) time.Duration {
+	if nextUpdate.IsZero() {
+		return defaultRevocationTTL
+	}
+	if ttl := time.Until(nextUpdate); ttl > 0 {
+		return ttl
+	}
+	return defaultRevocationTTL
+}
+
+// fetchOCSP performs a live OCSP request against the responder named in the
+// certificate's Authority Information Access extension. The request is bound
+// to ctx so it can't outlive the caller's timeout.
+func fetchOCSP(ctx context.Context, cert, issuer *x509.Certificate) (*ocsp.Response, bool) {
+	if len(cert.OCSPServer) == 0 {
+		return nil, false
+	}
+
+	reqBytes, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, responderURL := range cert.OCSPServer {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, responderURL, bytes.NewReader(reqBytes))
+		if err != nil {
+			continue
+		}
+		httpReq.Header.Set("Content-Type", "application/ocsp-request")
+
+		httpResp, err := http.DefaultClient.Do(httpReq)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		resp, err := ocsp.ParseResponse(body, issuer)
+		if err != nil {
+			continue
+		}
+		return resp, true
+	}
+
+	return nil, false
+}
+
+// fetchCRL fetches and parses the CRL named in the certificate's
+// CRLDistributionPoints extension, falling back to it only when OCSP is
+// unavailable or failed. The request is bound to ctx so it can't outlive the
+// caller's timeout.
+func fetchCRL(ctx context.Context, cert, issuer *x509.Certificate) (CertRevocation, time.Time, bool) {
+	for _, url := range cert.CRLDistributionPoints {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			continue
+		}
+
+		httpResp, err := http.DefaultClient.Do(httpReq)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		crl, err := x509.ParseRevocationList(body)
+		if err != nil {
+			continue
+		}
+		if crl.CheckSignatureFrom(issuer) != nil {
+			continue
+		}
+
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				reason := revoked.ReasonCode
+				return CertRevocation{Status: RevocationRevoked, Reason: &reason, Source: "crl"}, crl.NextUpdate, true
+			}
+		}
+
+		return CertRevocation{Status: RevocationGood, Source: "crl"}, crl.NextUpdate, true
+	}
+
+	return CertRevocation{}, time.Time{}, false
+}
+
+// ocspResponseToRevocation maps an OCSP response's status onto CertRevocation.
+func ocspResponseToRevocation(resp *ocsp.Response, stapled bool, source string) CertRevocation {
+	result := CertRevocation{Stapled: stapled, Source: source}
+
+	switch resp.Status {
+	case ocsp.Good:
+		result.Status = RevocationGood
+	case ocsp.Revoked:
+		result.Status = RevocationRevoked
+		reason := resp.RevocationReason
+		result.Reason = &reason
+	default:
+		result.Status = RevocationUnknown
+	}
+
+	return result
+}
+
+func revocationCacheKey(issuer, cert *x509.Certificate) string {
+	return fmt.Sprintf("%s:%s", hex.EncodeToString(issuer.RawSubjectPublicKeyInfo), cert.SerialNumber.String())
+}
+
+func lookupRevocationCache(key string) (CertRevocation, bool) {
+	revocationCacheMu.Lock()
+	defer revocationCacheMu.Unlock()
+
+	entry, ok := revocationCache[key]
+	if !ok {
+		return CertRevocation{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(revocationCache, key)
+		return CertRevocation{}, false
+	}
+	return entry.result, true
+}
+
+func storeRevocationCache(key string, result CertRevocation, ttl time.Duration) {
+	revocationCacheMu.Lock()
+	defer revocationCacheMu.Unlock()
+
+	revocationCache[key] = revocationCacheEntry{
+		result:    result,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	revocationStores++
+	if revocationStores%revocationSweepInterval == 0 {
+		sweepRevocationCache()
+	}
+}
+
+// sweepRevocationCache removes every expired entry. Called with
+// revocationCacheMu already held.
+func sweepRevocationCache() {
+	now := time.Now()
+	for key, entry := range revocationCache {
+		if now.After(entry.expiresAt) {
+			delete(revocationCache, key)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package infra
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func selfSignedCert(t *testing.T, serial int64) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return cert
+}
+
+func TestCheckRevocationUnknownWithoutIssuer(t *testing.T) {
+	leaf := selfSignedCert(t, 1)
+
+	status := CheckRevocation(context.Background(), []*x509.Certificate{leaf}, nil)
+	if len(status.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(status.Entries))
+	}
+	if status.Entries[0].Status != RevocationUnknown {
+		t.Fatalf("expected unknown status for a cert with no issuer in chain, got %q", status.Entries[0].Status)
+	}
+}
+
+func TestCheckRevocationWithoutOCSPOrCRLSourcesYieldsUnknown(t *testing.T) {
+	// Neither cert carries an OCSPServer or CRLDistributionPoints, so
+	// checkCertRevocation should fall through both lookups without making a
+	// network call and report unknown.
+	leaf := selfSignedCert(t, 2)
+	issuer := selfSignedCert(t, 3)
+
+	status := CheckRevocation(context.Background(), []*x509.Certificate{leaf, issuer}, nil)
+	if len(status.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(status.Entries))
+	}
+	if status.Entries[0].Status != RevocationUnknown {
+		t.Fatalf("expected unknown status for leaf with no revocation sources, got %q", status.Entries[0].Status)
+	}
+}
+
+func TestCheckRevocationHonorsAlreadyExpiredContext(t *testing.T) {
+	leaf := selfSignedCert(t, 4)
+	issuer := selfSignedCert(t, 5)
+	leaf.OCSPServer = []string{"http://127.0.0.1:0/ocsp"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		CheckRevocation(ctx, []*x509.Certificate{leaf, issuer}, nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("CheckRevocation did not return promptly with an already-expired context")
+	}
+}
+
+func TestTTLFromNextUpdate(t *testing.T) {
+	if ttl := ttlFromNextUpdate(time.Time{}); ttl != defaultRevocationTTL {
+		t.Fatalf("zero nextUpdate: got %v, want %v", ttl, defaultRevocationTTL)
+	}
+	if ttl := ttlFromNextUpdate(time.Now().Add(-time.Hour)); ttl != defaultRevocationTTL {
+		t.Fatalf("past nextUpdate: got %v, want %v", ttl, defaultRevocationTTL)
+	}
+	future := time.Now().Add(30 * time.Minute)
+	if ttl := ttlFromNextUpdate(future); ttl <= 0 || ttl > 30*time.Minute {
+		t.Fatalf("future nextUpdate: got %v, want roughly 30m", ttl)
+	}
+}
+
+func TestRevocationCacheLookupExpiresEntries(t *testing.T) {
+	key := "test-cache-key-expiry"
+	storeRevocationCache(key, CertRevocation{Status: RevocationGood}, -time.Second)
+
+	if _, ok := lookupRevocationCache(key); ok {
+		t.Fatal("expected an already-expired entry to be evicted on lookup")
+	}
+}
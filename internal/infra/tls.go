@@ -1,44 +1,117 @@
 package infra
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"time"
 )
 
 // CertInfo contains TLS certificate information.
 type CertInfo struct {
-	Protocol  string
-	Cipher    string
-	Issuer    string
-	Subject   string
-	ValidFrom uint64
-	ValidTo   uint64
+	Protocol           string
+	Cipher             string
+	Issuer             string
+	Subject            string
+	ValidFrom          uint64
+	ValidTo            uint64
+	SANs               []string
+	ALPN               string
+	NegotiatedProtocol string
+	KeyUsage           []string
+	SignatureAlgorithm string
+	SPKIFingerprint    string
+	Chain              []CertInfo
 }
 
-// ExtractCertInfo extracts certificate information from a TLS connection state.
+// ExtractCertInfo extracts certificate information from a TLS connection state,
+// including the full peer certificate chain beyond the leaf.
 func ExtractCertInfo(state *tls.ConnectionState) *CertInfo {
 	if state == nil {
 		return nil
 	}
 
 	info := &CertInfo{
-		Protocol: tlsVersionString(state.Version),
-		Cipher:   tls.CipherSuiteName(state.CipherSuite),
+		Protocol:           tlsVersionString(state.Version),
+		Cipher:             tls.CipherSuiteName(state.CipherSuite),
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		ALPN:               state.NegotiatedProtocol,
 	}
 
-	// Get peer certificate
 	if len(state.PeerCertificates) > 0 {
-		cert := state.PeerCertificates[0]
-		info.Subject = extractCN(cert.Subject.String())
-		info.Issuer = extractCN(cert.Issuer.String())
-		info.ValidFrom = uint64(cert.NotBefore.Unix())
-		info.ValidTo = uint64(cert.NotAfter.Unix())
+		leaf := buildCertInfo(state.PeerCertificates[0])
+		info.Issuer = leaf.Issuer
+		info.Subject = leaf.Subject
+		info.ValidFrom = leaf.ValidFrom
+		info.ValidTo = leaf.ValidTo
+		info.SANs = leaf.SANs
+		info.KeyUsage = leaf.KeyUsage
+		info.SignatureAlgorithm = leaf.SignatureAlgorithm
+		info.SPKIFingerprint = leaf.SPKIFingerprint
+
+		for _, chainCert := range state.PeerCertificates[1:] {
+			chainInfo := buildCertInfo(chainCert)
+			info.Chain = append(info.Chain, *chainInfo)
+		}
 	}
 
 	return info
 }
 
+// buildCertInfo extracts the fields ExtractCertInfo and the chain walk share,
+// so the leaf and every intermediate/root are inspected the same way.
+func buildCertInfo(cert *x509.Certificate) *CertInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return &CertInfo{
+		Subject:            extractCN(cert.Subject.String()),
+		Issuer:             extractCN(cert.Issuer.String()),
+		ValidFrom:          uint64(cert.NotBefore.Unix()),
+		ValidTo:            uint64(cert.NotAfter.Unix()),
+		SANs:               sans,
+		KeyUsage:           keyUsageStrings(cert),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SPKIFingerprint:    spkiFingerprint(cert),
+	}
+}
+
+// keyUsageStrings renders a certificate's x509.KeyUsage bitmask as human-readable names.
+func keyUsageStrings(cert *x509.Certificate) []string {
+	usages := []struct {
+		bit  x509.KeyUsage
+		name string
+	}{
+		{x509.KeyUsageDigitalSignature, "digitalSignature"},
+		{x509.KeyUsageContentCommitment, "contentCommitment"},
+		{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+		{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+		{x509.KeyUsageKeyAgreement, "keyAgreement"},
+		{x509.KeyUsageCertSign, "certSign"},
+		{x509.KeyUsageCRLSign, "crlSign"},
+		{x509.KeyUsageEncipherOnly, "encipherOnly"},
+		{x509.KeyUsageDecipherOnly, "decipherOnly"},
+	}
+
+	var names []string
+	for _, u := range usages {
+		if cert.KeyUsage&u.bit != 0 {
+			names = append(names, u.name)
+		}
+	}
+	return names
+}
+
+// spkiFingerprint returns the hex-encoded SHA-256 digest of the certificate's
+// subject public key info, suitable for pin comparison.
+func spkiFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:])
+}
+
 // IsCertValid checks if a certificate is currently valid.
 func IsCertValid(validFrom, validTo uint64) bool {
 	now := uint64(time.Now().Unix())
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+)
+
+// countingConn wraps a net.Conn, atomically accumulating bytes read/written
+// so callers can report actual bytes-on-wire distinct from the decoded body size.
+type countingConn struct {
+	net.Conn
+	bytesRead    *uint64
+	bytesWritten *uint64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		atomic.AddUint64(c.bytesRead, uint64(n))
+	}
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		atomic.AddUint64(c.bytesWritten, uint64(n))
+	}
+	return n, err
+}
+
+// wireMeter accumulates bytes read/written across every connection a single
+// ExecuteRequest call dials, e.g. across a redirect chain.
+type wireMeter struct {
+	bytesIn  uint64
+	bytesOut uint64
+}
+
+// wrapDialContext wraps dial so every connection it returns is counted into m.
+func (m *wireMeter) wrapDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, bytesRead: &m.bytesIn, bytesWritten: &m.bytesOut}, nil
+	}
+}
+
+func (m *wireMeter) read() (in, out uint64) {
+	return atomic.LoadUint64(&m.bytesIn), atomic.LoadUint64(&m.bytesOut)
+}
+
+// countingReader wraps an io.Reader, tallying bytes read so a caller
+// streaming a still-compressed response body can report the actual
+// compressed size without buffering it separately.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
@@ -2,7 +2,6 @@ package proxy
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
@@ -139,9 +138,9 @@ func (c *requestContext) addr() string {
 	return net.JoinHostPort(c.host, c.port)
 }
 
-// ExecuteRequest executes an HTTP request with detailed timing.
+// ExecuteRequest executes an HTTP request, recording detailed timing for every hop.
 func ExecuteRequest(request ProxyRequest) ProxyResponse {
-	timing := NewDetailedTiming()
+	overallStart := time.Now()
 
 	// Parse initial URL
 	ctx, err := newRequestContext(request.URL)
@@ -155,13 +154,16 @@ func ExecuteRequest(request ProxyRequest) ProxyResponse {
 	}
 	timeout := time.Duration(timeoutMS) * time.Millisecond
 
-	// DNS Resolution
-	timing.StartDNS()
-	dnsResult, err := infra.ResolveDNS(context.Background(), ctx.host)
+	// DNS Resolution (informational lookup for the initial host; the transport
+	// re-resolves per hop and that resolution is what the per-hop timing covers).
+	// Bounded to the request's own timeout so a DoH/DoT fallback hop against a
+	// slow upstream can't hang the request beyond what the caller asked for.
+	dnsCtx, dnsCancel := context.WithTimeout(context.Background(), timeout)
+	dnsResult, err := infra.ResolveDNS(dnsCtx, ctx.host)
+	dnsCancel()
 	if err != nil {
 		return NewErrorResponse(fmt.Sprintf("DNS lookup failed: %v", err), "DNS_ERROR")
 	}
-	timing.EndDNS()
 
 	var serverIP string
 	var resolvedIPs []string
@@ -174,8 +176,12 @@ func ExecuteRequest(request ProxyRequest) ProxyResponse {
 
 	// Track redirect chain
 	var redirectChain []RedirectHop
+	var proxyChain []RedirectHop
 	var tlsInfo *infra.CertInfo
+	var revocation infra.RevocationStatus
 	var httpVersion string
+	var hopTimings []TimingInfo
+	var proxyUsed string
 
 	requestHeaders := request.Headers
 	if requestHeaders == nil {
@@ -188,65 +194,59 @@ func ExecuteRequest(request ProxyRequest) ProxyResponse {
 		requestBodySize = &size
 	}
 
-	isFirstRequest := true
+	if request.Upstream != nil && request.ForceProtocol != nil && *request.ForceProtocol == ProtocolHTTP3 {
+		return NewErrorResponse("upstream proxy is not supported with HTTP/3 (QUIC)", "UNSUPPORTED_PROTOCOL")
+	}
+
+	meter := &wireMeter{}
 
 	for {
-		hopStart := time.Now()
+		hopTiming := NewDetailedTiming()
+
+		// Declared fresh per hop so a verification failure on an earlier
+		// redirect hop doesn't linger on the response once a later hop's
+		// handshake succeeds cleanly.
+		var tlsVerificationErr string
+
+		tlsConfig, err := buildTLSConfig(request.TLS, ctx.host, &tlsVerificationErr)
+		if err != nil {
+			return NewErrorResponse(err.Error(), "TLS_CONFIG_ERROR")
+		}
 
 		// Create HTTP client with custom transport for timing
 		transport := &http.Transport{
-			DialContext: func(dialCtx context.Context, network, addr string) (net.Conn, error) {
-				if isFirstRequest {
-					timing.StartTCP()
-				}
-				dialer := &net.Dialer{Timeout: timeout}
-				conn, err := dialer.DialContext(dialCtx, network, addr)
-				if isFirstRequest && err == nil {
-					timing.EndTCP()
-				}
-				return conn, err
-			},
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: false,
-			},
+			DialContext:         meter.wrapDialContext((&net.Dialer{Timeout: timeout}).DialContext),
+			TLSClientConfig:     tlsConfig,
 			TLSHandshakeTimeout: timeout,
 			DisableCompression:  false,
 		}
 
-		// Capture TLS info
-		if ctx.isHTTPS {
-			transport.DialTLSContext = func(dialCtx context.Context, network, addr string) (net.Conn, error) {
-				if isFirstRequest {
-					timing.StartTCP()
-				}
-				dialer := &net.Dialer{Timeout: timeout}
-				conn, err := dialer.DialContext(dialCtx, network, addr)
-				if err != nil {
-					return nil, err
-				}
-				if isFirstRequest {
-					timing.EndTCP()
-					timing.StartTLS()
-				}
-
-				tlsConn := tls.Client(conn, &tls.Config{
-					ServerName: ctx.host,
+		if request.Upstream != nil {
+			hopProxyAddr, err := applyUpstreamProxy(transport, request.Upstream, ctx.host, timeout, meter)
+			if err != nil {
+				return NewErrorResponse(err.Error(), "PROXY_CONFIG_ERROR")
+			}
+			if hopProxyAddr != "" {
+				proxyUsed = hopProxyAddr
+				// Recorded separately from redirectChain - this is informational
+				// (which upstream proxy handled the hop), not an HTTP redirect,
+				// and must not make a non-redirecting request look redirected.
+				proxyChain = append(proxyChain, RedirectHop{
+					URL:     ctx.url,
+					Status:  200,
+					Message: strPtr(fmt.Sprintf("CONNECT via %s (%s)", hopProxyAddr, request.Upstream.Scheme)),
+					Opaque:  boolPtr(true),
 				})
-				if err := tlsConn.HandshakeContext(dialCtx); err != nil {
-					conn.Close()
-					return nil, err
-				}
-				if isFirstRequest {
-					timing.EndTLS()
-					state := tlsConn.ConnectionState()
-					tlsInfo = infra.ExtractCertInfo(&state)
-				}
-				return tlsConn, nil
 			}
 		}
 
+		roundTripper, err := newRoundTripper(request.ForceProtocol, transport, transport.TLSClientConfig)
+		if err != nil {
+			return NewErrorResponse(err.Error(), "UNSUPPORTED_PROTOCOL")
+		}
+
 		client := &http.Client{
-			Transport: transport,
+			Transport: roundTripper,
 			Timeout:   timeout,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				// Don't follow redirects automatically - we handle them manually
@@ -264,6 +264,7 @@ func ExecuteRequest(request ProxyRequest) ProxyResponse {
 		if err != nil {
 			return NewErrorResponse(fmt.Sprintf("Failed to create request: %v", err), "REQUEST_BUILD_ERROR")
 		}
+		httpReq = httpReq.WithContext(hopTiming.WithClientTrace(httpReq.Context()))
 
 		// Set headers
 		for key, value := range request.Headers {
@@ -275,28 +276,22 @@ func ExecuteRequest(request ProxyRequest) ProxyResponse {
 			httpReq.Header.Set("Accept-Encoding", "gzip, deflate, br")
 		}
 
-		if isFirstRequest {
-			timing.StartRequest()
-		}
-
 		// Execute request
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			return NewErrorResponse(fmt.Sprintf("Request failed: %v", err), "REQUEST_FAILED")
 		}
 
-		if isFirstRequest {
-			timing.MarkTTFB()
-		}
+		if resp.TLS != nil {
+			tlsInfo = infra.ExtractCertInfo(resp.TLS)
 
-		// Read response
-		timing.StartDownload()
-		bodyBytes, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return NewErrorResponse(fmt.Sprintf("Failed to read body: %v", err), "BODY_READ_ERROR")
+			// Bounded to the request's own timeout so a slow/unresponsive OCSP
+			// responder or CRL host can't hang the request indefinitely - the
+			// revocation check otherwise sits outside client.Do's timeout.
+			revocationCtx, cancel := context.WithTimeout(context.Background(), timeout)
+			revocation = infra.CheckRevocation(revocationCtx, resp.TLS.PeerCertificates, resp.TLS.OCSPResponse)
+			cancel()
 		}
-		timing.EndDownload()
 
 		// Get headers
 		headers := make(map[string]string)
@@ -306,42 +301,69 @@ func ExecuteRequest(request ProxyRequest) ProxyResponse {
 			}
 		}
 
+		// Read response, decompressing directly off the wire: the zip-bomb
+		// ratio guard (internal/infra.DecoderRegistry) then sees the real
+		// compressed/decompressed byte counts as the body streams in, instead
+		// of only after the whole (possibly huge) compressed body has already
+		// been buffered.
+		hopTiming.StartDownload()
+		wireBody := &countingReader{r: resp.Body}
+		decompressor, err := infra.DecompressStream(wireBody, headers["content-encoding"])
+		if err != nil {
+			resp.Body.Close()
+			return NewErrorResponse(fmt.Sprintf("Failed to decompress body: %v", err), "DECOMPRESSION_ERROR")
+		}
+		bodyBytes, err := io.ReadAll(decompressor)
+		decompressor.Close()
+		resp.Body.Close()
+		if err != nil {
+			return NewErrorResponse(fmt.Sprintf("Failed to read body: %v", err), "BODY_READ_ERROR")
+		}
+		hopTiming.EndDownload()
+		compressedSize := wireBody.n
+
 		httpVersion = resp.Proto
+		hopTimingInfo := hopTiming.ToTimingInfo()
+		hopTimings = append(hopTimings, hopTimingInfo)
 
 		// Check for redirect
 		if resp.StatusCode >= 300 && resp.StatusCode < 400 {
 			location := resp.Header.Get("Location")
 			if location != "" {
-				hopDuration := uint64(time.Since(hopStart).Milliseconds())
 				currentURL := ctx.url
 				nextURL := ctx.updateFromRedirect(location)
 
 				redirectChain = append(redirectChain, RedirectHop{
 					URL:      currentURL,
 					Status:   uint16(resp.StatusCode),
-					Duration: hopDuration,
+					Duration: hopTimingInfo.Total,
 					Headers:  headers,
 					Message:  strPtr(fmt.Sprintf("Redirect to: %s", nextURL)),
+					Protocol: strPtr(httpVersion),
+					Timing:   &hopTimingInfo,
 				})
 
 				if len(redirectChain) >= MaxRedirects {
 					return NewErrorResponse("Too many redirects", "TOO_MANY_REDIRECTS")
 				}
 
-				isFirstRequest = false
 				continue
 			}
 		}
 
 		// Build response
+		wireBytesIn, wireBytesOut := meter.read()
 		return buildResponse(responseBuildParams{
 			status:          uint16(resp.StatusCode),
 			headers:         headers,
 			bodyBytes:       bodyBytes,
-			timing:          timing,
+			compressedSize:  compressedSize,
+			timing:          aggregateTimings(hopTimings, overallStart),
 			finalURL:        ctx.url,
 			redirectChain:   redirectChain,
+			proxyChain:      proxyChain,
 			tlsInfo:         tlsInfo,
+			revocation:      revocation,
 			httpVersion:     httpVersion,
 			serverIP:        serverIP,
 			requestHeaders:  requestHeaders,
@@ -349,10 +371,64 @@ func ExecuteRequest(request ProxyRequest) ProxyResponse {
 			hostname:        ctx.host,
 			port:            ctx.port,
 			resolvedIPs:     resolvedIPs,
+			proxyUsed:       proxyUsed,
+			tlsVerifyErr:    tlsVerificationErr,
+			wireBytesIn:     wireBytesIn,
+			wireBytesOut:    wireBytesOut,
 		})
 	}
 }
 
+// aggregateTimings sums each hop's per-phase durations into a single
+// TimingInfo covering the whole redirect chain, while TTFB reflects the final
+// hop (the one whose body the caller actually reads).
+func aggregateTimings(hops []TimingInfo, overallStart time.Time) TimingInfo {
+	total := TimingInfo{
+		Total: uint64(time.Since(overallStart).Milliseconds()),
+	}
+
+	var dns, tcp, tlsMS, download uint64
+	for _, hop := range hops {
+		if hop.DNS != nil {
+			dns += *hop.DNS
+		}
+		if hop.TCP != nil {
+			tcp += *hop.TCP
+		}
+		if hop.TLS != nil {
+			tlsMS += *hop.TLS
+		}
+		if hop.Download != nil {
+			download += *hop.Download
+		}
+	}
+
+	if dns > 0 {
+		total.DNS = uint64Ptr(dns)
+	}
+	if tcp > 0 {
+		total.TCP = uint64Ptr(tcp)
+	}
+	if tlsMS > 0 {
+		total.TLS = uint64Ptr(tlsMS)
+	}
+	if download > 0 {
+		total.Download = uint64Ptr(download)
+	}
+	if len(hops) > 0 {
+		total.TTFB = hops[len(hops)-1].TTFB
+	}
+
+	blocked := uint64(0)
+	total.Blocked = &blocked
+
+	return total
+}
+
 func strPtr(s string) *string {
 	return &s
 }
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"encoding/base64"
+
+	"zone.digit.tommie/internal/infra/export"
+)
+
+// ExportFormat returns the Exporter for a format token ("curl", "http" or
+// "har"), as used by the export format query parameter / IPC argument.
+func ExportFormat(format string) (export.Exporter, error) {
+	return export.ForFormat(format)
+}
+
+// ToExchange translates a completed ProxyRequest/ResponseData pair into the
+// export package's neutral Exchange, so it can be handed to any Exporter.
+func ToExchange(request ProxyRequest, data ResponseData) export.Exchange {
+	var requestBody []byte
+	if request.Body != nil {
+		requestBody = []byte(*request.Body)
+	}
+
+	var responseBody []byte
+	if data.IsBinary && data.BodyBase64 != nil {
+		responseBody, _ = base64.StdEncoding.DecodeString(*data.BodyBase64)
+	} else {
+		responseBody = []byte(data.Body)
+	}
+
+	protocol := ""
+	if data.Protocol != nil {
+		protocol = *data.Protocol
+	}
+
+	return export.Exchange{
+		Method:          request.Method,
+		URL:             data.URL,
+		HTTPVersion:     protocol,
+		RequestHeaders:  request.Headers,
+		RequestBody:     requestBody,
+		Status:          int(data.Status),
+		StatusText:      data.StatusText,
+		ResponseHeaders: data.Headers,
+		ResponseBody:    responseBody,
+		IsBinary:        data.IsBinary,
+		Timing:          toExportTiming(data.Timing),
+	}
+}
+
+// toExportTiming maps a TimingInfo (milliseconds, omitted phases nil) onto
+// export.Timing, which uses HAR's convention of -1 for "not measured".
+func toExportTiming(t TimingInfo) export.Timing {
+	return export.Timing{
+		DNS:     timingMS(t.DNS),
+		Connect: timingMS(t.TCP),
+		SSL:     timingMS(t.TLS),
+		Wait:    timingMS(t.TTFB),
+		Receive: timingMS(t.Download),
+	}
+}
+
+func timingMS(v *uint64) int64 {
+	if v == nil {
+		return -1
+	}
+	return int64(*v)
+}
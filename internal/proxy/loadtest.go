@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// LoadOptions configures a load-test run against a single request.
+type LoadOptions struct {
+	Concurrency int     `json:"concurrency"`
+	TargetRPS   float64 `json:"targetRps"`
+	DurationMS  uint64  `json:"durationMs"`
+}
+
+// ReportRecord is emitted for every completed request during a load test.
+type ReportRecord struct {
+	Cost       uint64  `json:"cost"` // Request latency in milliseconds
+	Code       int     `json:"code"`
+	Error      *string `json:"error,omitempty"`
+	ReadBytes  uint64  `json:"readBytes"`
+	WriteBytes uint64  `json:"writeBytes"`
+}
+
+// LoadSummary is the final report for a load-test run.
+type LoadSummary struct {
+	Requests int    `json:"requests"`
+	Errors   int    `json:"errors"`
+	P50      uint64 `json:"p50"`
+	P90      uint64 `json:"p90"`
+	P99      uint64 `json:"p99"`
+}
+
+// RunLoadTest fires opts.Concurrency workers at request, rate-limited to
+// opts.TargetRPS, for opts.DurationMS. onRecord is invoked for every
+// completed request so the caller can stream progress; the returned
+// LoadSummary covers the whole run's rolling latency histogram.
+func RunLoadTest(ctx context.Context, request ProxyRequest, opts LoadOptions, onRecord func(ReportRecord)) LoadSummary {
+	limit := rate.Inf
+	if opts.TargetRPS > 0 {
+		limit = rate.Limit(opts.TargetRPS)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	deadline := time.Now().Add(time.Duration(opts.DurationMS) * time.Millisecond)
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	var latencies []uint64
+	var errorCount int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				start := time.Now()
+				resp := ExecuteRequest(request)
+				cost := uint64(time.Since(start).Milliseconds())
+
+				record := ReportRecord{Cost: cost}
+				switch {
+				case resp.Success && resp.Data != nil:
+					record.Code = int(resp.Data.Status)
+					if resp.Data.WireBytesIn != nil {
+						record.ReadBytes = *resp.Data.WireBytesIn
+					}
+					if resp.Data.WireBytesOut != nil {
+						record.WriteBytes = *resp.Data.WireBytesOut
+					}
+				case resp.Error != nil:
+					atomic.AddInt64(&errorCount, 1)
+					message := resp.Error.Message
+					record.Error = &message
+				}
+
+				mu.Lock()
+				latencies = append(latencies, cost)
+				mu.Unlock()
+
+				onRecord(record)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	return LoadSummary{
+		Requests: len(latencies),
+		Errors:   int(errorCount),
+		P50:      percentile(latencies, 50),
+		P90:      percentile(latencies, 90),
+		P99:      percentile(latencies, 99),
+	}
+}
+
+// percentile returns the p-th percentile of a pre-sorted slice of latencies.
+func percentile(sorted []uint64, p int) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := (p * len(sorted)) / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
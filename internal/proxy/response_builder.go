@@ -14,11 +14,14 @@ import (
 type responseBuildParams struct {
 	status          uint16
 	headers         map[string]string
-	bodyBytes       []byte
-	timing          *DetailedTiming
+	bodyBytes       []byte // already decompressed by the caller
+	compressedSize  int    // bytes actually read off the wire before decompression
+	timing          TimingInfo
 	finalURL        string
 	redirectChain   []RedirectHop
+	proxyChain      []RedirectHop
 	tlsInfo         *infra.CertInfo
+	revocation      infra.RevocationStatus
 	httpVersion     string
 	serverIP        string
 	requestHeaders  map[string]string
@@ -26,6 +29,10 @@ type responseBuildParams struct {
 	hostname        string
 	port            string
 	resolvedIPs     []string
+	proxyUsed       string
+	tlsVerifyErr    string
+	wireBytesIn     uint64
+	wireBytesOut    uint64
 }
 
 // isBinaryContent determines if response body is likely binary based on content-type.
@@ -63,14 +70,12 @@ func buildResponse(params responseBuildParams) ProxyResponse {
 	contentEncoding := params.headers["content-encoding"]
 	isBinary := isBinaryContent(contentType)
 
-	// Decompress if needed
-	compressedSize := len(params.bodyBytes)
-	decompressResult, err := infra.Decompress(params.bodyBytes, contentEncoding)
-	if err != nil {
-		return NewErrorResponse(fmt.Sprintf("Decompression failed: %v", err), "DECOMPRESSION_ERROR")
-	}
-	decompressed := decompressResult.Data
+	// The caller already streamed the body through infra.DecompressStream,
+	// so params.bodyBytes is decompressed and params.compressedSize is the
+	// real wire byte count.
+	decompressed := params.bodyBytes
 	bodySize := len(decompressed)
+	compressedSize := params.compressedSize
 
 	// Convert body
 	var body string
@@ -126,6 +131,46 @@ func buildResponse(params responseBuildParams) ProxyResponse {
 			Valid:     &valid,
 			SANs:      params.tlsInfo.SANs,
 		}
+		if params.tlsInfo.ALPN != "" {
+			tlsInfoData.ALPN = strPtr(params.tlsInfo.ALPN)
+		}
+		if params.tlsInfo.NegotiatedProtocol != "" {
+			tlsInfoData.NegotiatedProtocol = strPtr(params.tlsInfo.NegotiatedProtocol)
+		}
+		if strings.Contains(params.httpVersion, "3") {
+			tlsInfoData.QUICVersion = strPtr("1")
+		}
+		if params.tlsInfo.SignatureAlgorithm != "" {
+			tlsInfoData.SignatureAlgorithm = strPtr(params.tlsInfo.SignatureAlgorithm)
+		}
+		if params.tlsInfo.SPKIFingerprint != "" {
+			tlsInfoData.SPKIFingerprint = strPtr(params.tlsInfo.SPKIFingerprint)
+		}
+		tlsInfoData.KeyUsage = params.tlsInfo.KeyUsage
+		tlsInfoData.Revocation = revocationEntry(params.revocation, 0)
+
+		for i, chainCert := range params.tlsInfo.Chain {
+			validFrom, validTo := chainCert.ValidFrom, chainCert.ValidTo
+			entry := CertChainEntry{
+				Subject:    strPtr(chainCert.Subject),
+				Issuer:     strPtr(chainCert.Issuer),
+				ValidFrom:  &validFrom,
+				ValidTo:    &validTo,
+				SANs:       chainCert.SANs,
+				KeyUsage:   chainCert.KeyUsage,
+				Revocation: revocationEntry(params.revocation, i+1),
+			}
+			if chainCert.SignatureAlgorithm != "" {
+				entry.SignatureAlgorithm = strPtr(chainCert.SignatureAlgorithm)
+			}
+			if chainCert.SPKIFingerprint != "" {
+				entry.SPKIFingerprint = strPtr(chainCert.SPKIFingerprint)
+			}
+			tlsInfoData.Chain = append(tlsInfoData.Chain, entry)
+		}
+	}
+	if tlsInfoData != nil && params.tlsVerifyErr != "" {
+		tlsInfoData.VerificationError = strPtr(params.tlsVerifyErr)
 	}
 
 	serverSoftware := params.headers["server"]
@@ -154,6 +199,11 @@ func buildResponse(params responseBuildParams) ProxyResponse {
 		portPtr = &params.port
 	}
 
+	var proxyUsedPtr *string
+	if params.proxyUsed != "" {
+		proxyUsedPtr = &params.proxyUsed
+	}
+
 	fromCache := false
 	resourceType := "fetch"
 
@@ -162,6 +212,11 @@ func buildResponse(params responseBuildParams) ProxyResponse {
 		redirectChainPtr = params.redirectChain
 	}
 
+	var proxyChainPtr []RedirectHop
+	if len(params.proxyChain) > 0 {
+		proxyChainPtr = params.proxyChain
+	}
+
 	data := ResponseData{
 		Status:          params.status,
 		StatusText:      statustext.Get(int(params.status)),
@@ -171,10 +226,11 @@ func buildResponse(params responseBuildParams) ProxyResponse {
 		BodyBase64:      bodyBase64,
 		IsBinary:        isBinary,
 		Size:            bodySize,
-		Timing:          params.timing.ToTimingInfo(),
+		Timing:          params.timing,
 		URL:             params.finalURL,
 		Redirected:      len(params.redirectChain) > 0,
 		RedirectChain:   redirectChainPtr,
+		ProxyChain:      proxyChainPtr,
 		TLS:             tlsInfoData,
 		SizeBreakdown:   sizeBreakdown,
 		ServerIP:        serverIPPtr,
@@ -187,11 +243,33 @@ func buildResponse(params responseBuildParams) ProxyResponse {
 		Hostname:        hostnamePtr,
 		Port:            portPtr,
 		ResolvedIPs:     params.resolvedIPs,
+		ProxyUsed:       proxyUsedPtr,
+		WireBytesIn:     uint64Ptr(params.wireBytesIn),
+		WireBytesOut:    uint64Ptr(params.wireBytesOut),
 	}
 
 	return NewSuccessResponse(data)
 }
 
+// revocationEntry maps the infra.CertRevocation at chain position i, if any,
+// onto the proxy package's RevocationEntry.
+func revocationEntry(status infra.RevocationStatus, i int) *RevocationEntry {
+	if i >= len(status.Entries) {
+		return nil
+	}
+
+	result := status.Entries[i]
+	entry := &RevocationEntry{
+		Status:  result.Status,
+		Reason:  result.Reason,
+		Stapled: result.Stapled,
+	}
+	if result.Source != "" {
+		entry.Source = strPtr(result.Source)
+	}
+	return entry
+}
+
 // uint64Ptr creates a pointer to a uint64.
 func uint64Ptr(v uint64) *uint64 {
 	return &v
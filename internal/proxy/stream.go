@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamChunkSize is the size of each chunk read from a streamed response body.
+const StreamChunkSize = 64 * 1024
+
+// StreamHeaders is reported once a streamed response's headers are available.
+type StreamHeaders struct {
+	Status  uint16            `json:"status"`
+	Headers map[string]string `json:"headers"`
+}
+
+// StreamChunk is a single chunk of a streamed response body.
+type StreamChunk struct {
+	DataBase64 string `json:"dataBase64"`
+	Offset     int    `json:"offset"`
+}
+
+// StreamRequest executes request and reads the response body in bounded
+// chunks instead of buffering it like ExecuteRequest does, so large downloads
+// and long-lived streams (SSE, NDJSON) don't have to fit in memory. onHeaders
+// is called once the response headers arrive, onChunk once per chunk read.
+// Cancel ctx to abort the request and the read loop.
+func StreamRequest(ctx context.Context, request ProxyRequest, onHeaders func(StreamHeaders), onChunk func(StreamChunk) error) error {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
+	}
+
+	roundTripper, err := newRoundTripper(request.ForceProtocol, transport, transport.TLSClientConfig)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Transport: roundTripper}
+
+	var bodyReader io.Reader
+	if request.Body != nil {
+		bodyReader = strings.NewReader(*request.Body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, request.Method, request.URL, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, value := range request.Headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	headers := make(map[string]string)
+	for key, values := range resp.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(key)] = values[0]
+		}
+	}
+	onHeaders(StreamHeaders{Status: uint16(resp.StatusCode), Headers: headers})
+
+	buf := make([]byte, StreamChunkSize)
+	offset := 0
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			chunk := StreamChunk{
+				DataBase64: base64.StdEncoding.EncodeToString(buf[:n]),
+				Offset:     offset,
+			}
+			if err := onChunk(chunk); err != nil {
+				return err
+			}
+			offset += n
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read body: %w", readErr)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package proxy
 
-import "time"
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"time"
+)
 
 // DetailedTiming tracks timing measurements for HTTP request phases.
 type DetailedTiming struct {
@@ -116,6 +121,33 @@ func (t *DetailedTiming) MarkTTFB() {
 	t.TTFB = &now
 }
 
+// WithClientTrace attaches an httptrace.ClientTrace to ctx that drives this
+// timing's phases off the connection events for a single hop, instead of the
+// caller having to wrap DialContext/DialTLSContext by hand.
+func (t *DetailedTiming) WithClientTrace(ctx context.Context) context.Context {
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.StartDNS() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.EndDNS() },
+		ConnectStart: func(network, addr string) {
+			t.StartTCP()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.EndTCP()
+			}
+		},
+		TLSHandshakeStart: func() { t.StartTLS() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.EndTLS()
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { t.StartRequest() },
+		GotFirstResponseByte: func() { t.MarkTTFB() },
+	}
+	return httptrace.WithClientTrace(ctx, trace)
+}
+
 // StartDownload starts the download timing phase.
 func (t *DetailedTiming) StartDownload() {
 	now := time.Now()
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// tlsVersions maps the user-facing version strings accepted in TLSOptions to
+// the crypto/tls numeric constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// buildTLSConfig turns TLSOptions into a *tls.Config for serverName. When
+// InsecureSkipVerify is set, chain verification still runs through
+// VerifyPeerCertificate so the outcome can be reported back to the caller
+// instead of silently discarded; verificationErr is filled in once the
+// handshake completes.
+func buildTLSConfig(opts *TLSOptions, serverName string, verificationErr *string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: serverName,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	if opts == nil {
+		return cfg, nil
+	}
+
+	if opts.ServerName != nil && *opts.ServerName != "" {
+		cfg.ServerName = *opts.ServerName
+	}
+
+	if opts.MinVersion != nil {
+		version, ok := tlsVersions[*opts.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS min version: %s", *opts.MinVersion)
+		}
+		cfg.MinVersion = version
+	}
+
+	if opts.MaxVersion != nil {
+		version, ok := tlsVersions[*opts.MaxVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS max version: %s", *opts.MaxVersion)
+		}
+		cfg.MaxVersion = version
+	}
+
+	var roots *x509.CertPool
+	if len(opts.CACertsPEM) > 0 {
+		roots = x509.NewCertPool()
+		for _, pem := range opts.CACertsPEM {
+			if !roots.AppendCertsFromPEM([]byte(pem)) {
+				return nil, fmt.Errorf("failed to parse CA certificate")
+			}
+		}
+		cfg.RootCAs = roots
+	}
+
+	if opts.ClientCertPEM != "" && opts.ClientKeyPEM != "" {
+		cert, err := tls.X509KeyPair([]byte(opts.ClientCertPEM), []byte(opts.ClientKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.InsecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			verifyChain(rawCerts, roots, cfg.ServerName, verificationErr)
+			return nil
+		}
+	}
+
+	return cfg, nil
+}
+
+// verifyChain manually validates a raw certificate chain and records any
+// failure in verificationErr, so a caller that trusted the connection despite
+// an invalid certificate can still surface why it was invalid.
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool, serverName string, verificationErr *string) {
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			*verificationErr = err.Error()
+			return
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		*verificationErr = err.Error()
+	}
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+)
+
+// Supported values for ProxyRequest.ForceProtocol.
+const (
+	ProtocolHTTP1 = "http1"
+	ProtocolHTTP2 = "http2"
+	ProtocolH2C   = "h2c"
+	ProtocolHTTP3 = "http3"
+)
+
+// newRoundTripper builds the RoundTripper for the requested protocol. base is
+// the *http.Transport already wired up with the timeout-bound dialer; for
+// http1 and http2 it is reused directly so httptrace hooks attached to the
+// request context keep firing, while h2c and http3 need their own transport
+// and fall back to whatever sub-timing the respective library exposes.
+func newRoundTripper(forceProtocol *string, base *http.Transport, tlsConfig *tls.Config) (http.RoundTripper, error) {
+	protocol := ProtocolHTTP1
+	if forceProtocol != nil && *forceProtocol != "" {
+		protocol = *forceProtocol
+	}
+
+	switch protocol {
+	case ProtocolHTTP1:
+		// Disabling HTTP/2 via an empty (non-nil) TLSNextProto isn't enough on
+		// its own: if the ALPN offer still includes "h2" and the server picks
+		// it, net/http falls back to reading HTTP/1.1 off what is actually an
+		// HTTP/2-framed connection. Strip "h2" from the ALPN offer too, so the
+		// server only ever sees http/1.1 on the table.
+		base.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if base.TLSClientConfig != nil {
+			base.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		}
+		return base, nil
+	case ProtocolHTTP2:
+		h2Transport, err := http2.ConfigureTransports(base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure HTTP/2 transport: %w", err)
+		}
+		// http2.Transport dials its own connections directly (via tls.Dial)
+		// when DialTLSContext is unset, bypassing base.DialContext - and with
+		// it the wire-byte meter executor.go wraps that dialer with. Dial
+		// through base.DialContext ourselves and do the TLS handshake here
+		// instead, so forced-HTTP/2 requests are metered the same as every
+		// other protocol.
+		h2Transport.DialTLSContext = func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			rawConn, err := base.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn := tls.Client(rawConn, cfg)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				rawConn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		}
+		return base, nil
+	case ProtocolH2C:
+		return &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return base.DialContext(ctx, network, addr)
+			},
+		}, nil
+	case ProtocolHTTP3:
+		return &http3.RoundTripper{
+			TLSClientConfig: tlsConfig,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+}
@@ -3,11 +3,45 @@ package proxy
 
 // ProxyRequest represents an incoming proxy request from the frontend.
 type ProxyRequest struct {
-	Method  string            `json:"method"`
-	URL     string            `json:"url"`
-	Headers map[string]string `json:"headers"`
-	Body    *string           `json:"body,omitempty"`
-	Timeout *uint64           `json:"timeout,omitempty"` // Timeout in milliseconds
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers"`
+	Body          *string           `json:"body,omitempty"`
+	Timeout       *uint64           `json:"timeout,omitempty"`       // Timeout in milliseconds
+	ForceProtocol *string           `json:"forceProtocol,omitempty"` // "http1", "http2", "h2c" or "http3"
+	Upstream      *UpstreamProxy    `json:"upstream,omitempty"`
+	TLS           *TLSOptions       `json:"tls,omitempty"`
+}
+
+// TLSOptions configures custom TLS behavior for a request: trusting private
+// CAs, presenting a client certificate for mTLS, pinning protocol versions,
+// or overriding SNI.
+type TLSOptions struct {
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`
+	CACertsPEM         []string `json:"caCertsPem,omitempty"`
+	ClientCertPEM      string   `json:"clientCertPem,omitempty"`
+	ClientKeyPEM       string   `json:"clientKeyPem,omitempty"`
+	MinVersion         *string  `json:"minVersion,omitempty"` // "1.0".."1.3"
+	MaxVersion         *string  `json:"maxVersion,omitempty"`
+	ServerName         *string  `json:"serverName,omitempty"` // SNI override
+}
+
+// TLSProfile is a named TLSOptions bundle persisted so the frontend can bind
+// a saved profile (e.g. a client certificate) to a request by ID.
+type TLSProfile struct {
+	ID   string     `json:"id"`
+	Name string     `json:"name"`
+	TLS  TLSOptions `json:"tls"`
+}
+
+// UpstreamProxy configures an upstream proxy the request is routed through.
+type UpstreamProxy struct {
+	Scheme   string   `json:"scheme"` // "http", "https" or "socks5"
+	Host     string   `json:"host"`
+	Port     string   `json:"port"`
+	Username *string  `json:"username,omitempty"`
+	Password *string  `json:"password,omitempty"`
+	NoProxy  []string `json:"noProxy,omitempty"` // Hosts/suffixes to bypass the proxy for
 }
 
 // TimingInfo contains detailed timing information for an HTTP request.
@@ -29,17 +63,52 @@ type RedirectHop struct {
 	Headers  map[string]string `json:"headers,omitempty"`
 	Opaque   *bool             `json:"opaque,omitempty"`
 	Message  *string           `json:"message,omitempty"`
+	Protocol *string           `json:"protocol,omitempty"` // Negotiated protocol for this hop, e.g. "HTTP/2"
+	Timing   *TimingInfo       `json:"timing,omitempty"`
 }
 
 // TLSInfo contains TLS/SSL certificate information.
 type TLSInfo struct {
-	Protocol  *string `json:"protocol,omitempty"`
-	Cipher    *string `json:"cipher,omitempty"`
-	Issuer    *string `json:"issuer,omitempty"`
-	Subject   *string `json:"subject,omitempty"`
-	ValidFrom *uint64 `json:"validFrom,omitempty"`
-	ValidTo   *uint64 `json:"validTo,omitempty"`
-	Valid     *bool   `json:"valid,omitempty"`
+	Protocol           *string          `json:"protocol,omitempty"`
+	Cipher             *string          `json:"cipher,omitempty"`
+	Issuer             *string          `json:"issuer,omitempty"`
+	Subject            *string          `json:"subject,omitempty"`
+	ValidFrom          *uint64          `json:"validFrom,omitempty"`
+	ValidTo            *uint64          `json:"validTo,omitempty"`
+	Valid              *bool            `json:"valid,omitempty"`
+	SANs               []string         `json:"sans,omitempty"`
+	ALPN               *string          `json:"alpn,omitempty"`
+	NegotiatedProtocol *string          `json:"negotiatedProtocol,omitempty"`
+	QUICVersion        *string          `json:"quicVersion,omitempty"`
+	KeyUsage           []string         `json:"keyUsage,omitempty"`
+	SignatureAlgorithm *string          `json:"signatureAlgorithm,omitempty"`
+	SPKIFingerprint    *string          `json:"spkiFingerprint,omitempty"`
+	Revocation         *RevocationEntry `json:"revocation,omitempty"`
+	Chain              []CertChainEntry `json:"chain,omitempty"`
+	VerificationError  *string          `json:"verificationError,omitempty"`
+}
+
+// RevocationEntry is a certificate's revocation result, surfaced so the UI
+// can render an indicator similar to a browser's dev tools security panel.
+type RevocationEntry struct {
+	Status  string  `json:"status"` // "good", "revoked" or "unknown"
+	Reason  *int    `json:"reason,omitempty"`
+	Stapled bool    `json:"stapled"`
+	Source  *string `json:"source,omitempty"` // "ocsp-staple", "ocsp" or "crl"
+}
+
+// CertChainEntry describes one certificate in a validated TLS chain, beyond
+// the leaf certificate already summarized by the surrounding TLSInfo.
+type CertChainEntry struct {
+	Subject            *string          `json:"subject,omitempty"`
+	Issuer             *string          `json:"issuer,omitempty"`
+	ValidFrom          *uint64          `json:"validFrom,omitempty"`
+	ValidTo            *uint64          `json:"validTo,omitempty"`
+	SANs               []string         `json:"sans,omitempty"`
+	KeyUsage           []string         `json:"keyUsage,omitempty"`
+	SignatureAlgorithm *string          `json:"signatureAlgorithm,omitempty"`
+	SPKIFingerprint    *string          `json:"spkiFingerprint,omitempty"`
+	Revocation         *RevocationEntry `json:"revocation,omitempty"`
 }
 
 // SizeBreakdown contains response size information.
@@ -55,27 +124,37 @@ type SizeBreakdown struct {
 
 // ResponseData contains successful response data matching extension protocol.
 type ResponseData struct {
-	Status          uint16            `json:"status"`
-	StatusText      string            `json:"statusText"`
-	Headers         map[string]string `json:"headers"`
-	RequestHeaders  map[string]string `json:"requestHeaders,omitempty"`
-	Body            string            `json:"body"`
-	BodyBase64      *string           `json:"bodyBase64,omitempty"`
-	IsBinary        bool              `json:"isBinary"`
-	Size            int               `json:"size"`
-	Timing          TimingInfo        `json:"timing"`
-	URL             string            `json:"url"`
-	Redirected      bool              `json:"redirected"`
-	RedirectChain   []RedirectHop     `json:"redirectChain,omitempty"`
-	TLS             *TLSInfo          `json:"tls,omitempty"`
-	SizeBreakdown   *SizeBreakdown    `json:"sizeBreakdown,omitempty"`
-	ServerIP        *string           `json:"serverIp,omitempty"`
-	Protocol        *string           `json:"protocol,omitempty"`
-	FromCache       *bool             `json:"fromCache,omitempty"`
-	ResourceType    *string           `json:"resourceType,omitempty"`
-	RequestBodySize *int              `json:"requestBodySize,omitempty"`
-	Connection      *string           `json:"connection,omitempty"`
-	ServerSoftware  *string           `json:"serverSoftware,omitempty"`
+	Status         uint16            `json:"status"`
+	StatusText     string            `json:"statusText"`
+	Headers        map[string]string `json:"headers"`
+	RequestHeaders map[string]string `json:"requestHeaders,omitempty"`
+	Body           string            `json:"body"`
+	BodyBase64     *string           `json:"bodyBase64,omitempty"`
+	IsBinary       bool              `json:"isBinary"`
+	Size           int               `json:"size"`
+	Timing         TimingInfo        `json:"timing"`
+	URL            string            `json:"url"`
+	Redirected     bool              `json:"redirected"`
+	RedirectChain  []RedirectHop     `json:"redirectChain,omitempty"`
+	// ProxyChain records the CONNECT hop(s) made through an upstream proxy.
+	// Unlike RedirectChain, its presence never implies Redirected - a proxied
+	// request with zero HTTP redirects still populates this.
+	ProxyChain      []RedirectHop  `json:"proxyChain,omitempty"`
+	TLS             *TLSInfo       `json:"tls,omitempty"`
+	SizeBreakdown   *SizeBreakdown `json:"sizeBreakdown,omitempty"`
+	ServerIP        *string        `json:"serverIp,omitempty"`
+	Protocol        *string        `json:"protocol,omitempty"`
+	FromCache       *bool          `json:"fromCache,omitempty"`
+	ResourceType    *string        `json:"resourceType,omitempty"`
+	RequestBodySize *int           `json:"requestBodySize,omitempty"`
+	Connection      *string        `json:"connection,omitempty"`
+	ServerSoftware  *string        `json:"serverSoftware,omitempty"`
+	Hostname        *string        `json:"hostname,omitempty"`
+	Port            *string        `json:"port,omitempty"`
+	ResolvedIPs     []string       `json:"resolvedIps,omitempty"`
+	ProxyUsed       *string        `json:"proxyUsed,omitempty"`
+	WireBytesIn     *uint64        `json:"wireBytesIn,omitempty"`
+	WireBytesOut    *uint64        `json:"wireBytesOut,omitempty"`
 }
 
 // ErrorData contains error information matching extension protocol.
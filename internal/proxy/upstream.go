@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// matchesNoProxy reports whether host matches any entry in noProxy, either
+// exactly or as a suffix (".example.com" matches "api.example.com").
+func matchesNoProxy(host string, noProxy []string) bool {
+	for _, entry := range noProxy {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if host == entry || strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyUpstreamProxy wires transport to route connections through upstream,
+// unless host is covered by upstream.NoProxy. It returns the proxy address
+// actually used, or "" if the request bypassed the proxy. meter is the same
+// wireMeter that transport.DialContext was already wrapped with, so a
+// protocol branch that has to replace DialContext outright (SOCKS5, below)
+// can still report accurate wire bytes.
+func applyUpstreamProxy(transport *http.Transport, upstream *UpstreamProxy, host string, timeout time.Duration, meter *wireMeter) (string, error) {
+	if upstream == nil || matchesNoProxy(host, upstream.NoProxy) {
+		return "", nil
+	}
+
+	addr := net.JoinHostPort(upstream.Host, upstream.Port)
+
+	switch upstream.Scheme {
+	case "http", "https":
+		proxyURL := &url.URL{Scheme: upstream.Scheme, Host: addr}
+		if upstream.Username != nil {
+			password := ""
+			if upstream.Password != nil {
+				password = *upstream.Password
+			}
+			proxyURL.User = url.UserPassword(*upstream.Username, password)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		return addr, nil
+	case "socks5":
+		var auth *proxy.Auth
+		if upstream.Username != nil {
+			password := ""
+			if upstream.Password != nil {
+				password = *upstream.Password
+			}
+			auth = &proxy.Auth{User: *upstream.Username, Password: password}
+		}
+
+		forward := meteredDialer(meter.wrapDialContext((&net.Dialer{Timeout: timeout}).DialContext))
+		dialer, err := proxy.SOCKS5("tcp", addr, auth, forward)
+		if err != nil {
+			return "", fmt.Errorf("failed to configure SOCKS5 proxy: %w", err)
+		}
+
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return "", fmt.Errorf("SOCKS5 dialer does not support context-aware dialing")
+		}
+
+		transport.DialContext = contextDialer.DialContext
+		transport.DialTLSContext = nil
+		return addr, nil
+	default:
+		return "", fmt.Errorf("unsupported upstream proxy scheme: %s", upstream.Scheme)
+	}
+}
+
+// meteredDialer adapts a metered dial function to proxy.Dialer and
+// proxy.ContextDialer, so the SOCKS5 handshake with the proxy is counted too.
+type meteredDialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (d meteredDialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+func (d meteredDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
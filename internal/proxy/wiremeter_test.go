@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestForcedHTTP2RequestIsMetered(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	meter := &wireMeter{}
+	base := &http.Transport{
+		DialContext:     meter.wrapDialContext((&net.Dialer{}).DialContext),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+
+	protocol := ProtocolHTTP2
+	rt, err := newRoundTripper(&protocol, base, base.TLSClientConfig)
+	if err != nil {
+		t.Fatalf("newRoundTripper: %v", err)
+	}
+
+	client := &http.Client{Transport: rt}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected HTTP/2, got %s", resp.Proto)
+	}
+
+	in, out := meter.read()
+	if in == 0 || out == 0 {
+		t.Fatalf("expected non-zero wire bytes for a forced-HTTP/2 request (DialTLSContext must dial through base.DialContext), got in=%d out=%d", in, out)
+	}
+}
+
+// TestSOCKS5UpstreamDialIsMetered confirms applyUpstreamProxy's SOCKS5 branch
+// still routes through the wireMeter it's handed, even though it has to
+// replace transport.DialContext outright to point at the SOCKS5 dialer.
+func TestSOCKS5UpstreamDialIsMetered(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn) // never reply, so the client times out below
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("SplitHostPort: %v", err)
+	}
+
+	transport := &http.Transport{}
+	meter := &wireMeter{}
+	upstream := &UpstreamProxy{Scheme: "socks5", Host: host, Port: port}
+
+	if _, err := applyUpstreamProxy(transport, upstream, "example.test", time.Second, meter); err != nil {
+		t.Fatalf("applyUpstreamProxy: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	conn, err := transport.DialContext(ctx, "tcp", "example.test:443")
+	if conn != nil {
+		conn.Close()
+	}
+	if err == nil {
+		t.Fatal("expected the SOCKS5 handshake to time out against a server that never replies")
+	}
+
+	_, out := meter.read()
+	if out == 0 {
+		t.Fatal("expected non-zero wire bytes out from the SOCKS5 greeting, got 0")
+	}
+}
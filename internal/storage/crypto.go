@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+// keySize is the AES-256 key size: the passphrase-derived KEK, the DEK it
+// wraps, and every per-store key derived from the DEK are all this size.
+const keySize = 32
+
+// saltSize is the size of the random salt persisted in the meta table and
+// fed into Argon2id alongside the caller's passphrase.
+const saltSize = 16
+
+// Argon2id parameters for deriving the KEK from a passphrase, following the
+// OWASP baseline recommendation for an interactive unlock (time=1 at 64 MiB
+// keeps this well under a second on commodity hardware).
+const (
+	argon2Time      = 1
+	argon2MemoryKiB = 64 * 1024
+	argon2Threads   = 4
+)
+
+// deriveKEK derives the database's key-encryption-key from a passphrase and
+// salt via Argon2id.
+func deriveKEK(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2MemoryKiB, argon2Threads, keySize)
+}
+
+// deriveStoreKey derives a per-store AES-256 key from the database's data
+// encryption key (DEK) via HKDF, so a key leaked from one store doesn't
+// expose the others.
+func deriveStoreKey(dek []byte, store string) ([]byte, error) {
+	key := make([]byte, keySize)
+	kdf := hkdf.New(sha256.New, dek, nil, []byte("tommie-storage:"+store))
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, fmt.Errorf("failed to derive store key: %w", err)
+	}
+	return key, nil
+}
+
+// encryptValue seals plaintext with AES-256-GCM under key, returning the
+// nonce-prefixed ciphertext base64-encoded so it fits the storage table's
+// TEXT value column.
+func encryptValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue reverses encryptValue.
+func decryptValue(key []byte, encoded string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext encoding: %w", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:nonceSize], sealed[nonceSize:]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decryption failed: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
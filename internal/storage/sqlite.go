@@ -1,56 +1,209 @@
-// Package storage provides SQLite-based persistent storage for the desktop app.
+// Package storage provides SQLite-based persistent storage for the desktop
+// app. Values are encrypted at rest with AES-256-GCM using a per-store key
+// derived from a single data-encryption-key (DEK) via HKDF. The DEK itself
+// is generated once and persisted wrapped (encrypted) by a key-encryption-key
+// (KEK) derived from a caller-supplied passphrase via Argon2id - this
+// envelope means Rekey only has to re-wrap the DEK under a new KEK, not
+// re-encrypt every row, and unlike a random key written to a sibling file
+// the data is only as exposed as the passphrase is. Access to each store is
+// additionally gated by a capability/token grant, so sharing the database
+// file between callers with differing privileges doesn't imply sharing
+// every store in it.
 package storage
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// Database wraps a SQLite connection with thread-safe access.
+// dekMetaKey is where the wrapped data-encryption-key is persisted in the
+// meta table. Rekey only ever re-wraps this single value under a new KEK -
+// the storage table is never touched - so rotating the passphrase is O(1)
+// regardless of how much data has been stored.
+const dekMetaKey = "wrapped_dek"
+
+// ErrInvalidPassphrase is returned by New and Rekey when the supplied
+// passphrase doesn't unwrap the database's data encryption key.
+var ErrInvalidPassphrase = errors.New("invalid passphrase")
+
+// ErrNotAuthorized is returned by Get, Set, Remove, Has, Clear and Keys when
+// the context passed in carries no token, or a token that hasn't been
+// Grant-ed the capability the operation requires.
+var ErrNotAuthorized = errors.New("not authorized for this store and capability")
+
+// Capability is a permission a token can be Grant-ed against a store.
+type Capability string
+
+const (
+	// CapRead permits Get, Has and Keys.
+	CapRead Capability = "read"
+	// CapWrite permits Set and Remove.
+	CapWrite Capability = "write"
+	// CapClear permits Clear.
+	CapClear Capability = "clear"
+)
+
+// tokenContextKey is the context key WithToken stores a caller's token
+// under, so Authorize (and the CRUD methods that wrap it) can find it.
+type tokenContextKey struct{}
+
+// WithToken attaches token to ctx for a subsequent Database call. A context
+// with no token - or a token nobody has Grant-ed the relevant capability to
+// - is always denied.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+func tokenFromContext(ctx context.Context) (string, bool) {
+	token, _ := ctx.Value(tokenContextKey{}).(string)
+	return token, token != ""
+}
+
+// Options configures how a Database's key-encryption-key is derived.
+type Options struct {
+	// Passphrase unlocks (or, on first run, creates) the database's data
+	// encryption key via Argon2id. Required.
+	Passphrase string
+}
+
+// Database wraps a SQLite connection with thread-safe, encrypted,
+// capability-gated access.
 type Database struct {
-	db *sql.DB
-	mu sync.Mutex
+	db   *sql.DB
+	dek  []byte
+	salt []byte
+	mu   sync.Mutex
 }
 
-// New creates a new database connection at the specified path.
-func New(path string) (*Database, error) {
+// New creates a new database connection at the specified path, deriving (or,
+// on first run, creating) its data-encryption-key from opts.Passphrase via a
+// passphrase-derived KEK. The KDF salt is persisted in the database's own
+// meta table rather than a sibling file, since unlike a key file it's
+// useless to an attacker without the passphrase too. Returns
+// ErrInvalidPassphrase if the database already exists and opts.Passphrase
+// doesn't unwrap its stored data encryption key.
+func New(path string, opts Options) (*Database, error) {
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Enable WAL mode for better concurrency
 	if _, err := db.Exec("PRAGMA journal_mode=WAL;"); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	// Create storage table if not exists
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS storage (
+	if err := createSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	salt, err := loadOrCreateSalt(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	kek := deriveKEK(opts.Passphrase, salt)
+	dek, err := loadOrCreateDEK(db, kek)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Database{db: db, dek: dek, salt: salt}, nil
+}
+
+func createSchema(db *sql.DB) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS meta (
+			key TEXT PRIMARY KEY,
+			value BLOB NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS storage (
 			store TEXT NOT NULL,
 			key TEXT NOT NULL,
 			value TEXT NOT NULL,
 			updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
 			PRIMARY KEY (store, key)
-		)
-	`)
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create storage table: %w", err)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_storage_store ON storage(store)`,
+		// grants maps a (store, capability) pair to every token authorized
+		// for it, so two callers sharing one database file can hold
+		// different privileges over the same or different stores.
+		`CREATE TABLE IF NOT EXISTS grants (
+			store TEXT NOT NULL,
+			capability TEXT NOT NULL,
+			token TEXT NOT NULL,
+			granted_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+			PRIMARY KEY (store, capability, token)
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to apply schema: %w", err)
+		}
 	}
+	return nil
+}
 
-	// Create index for faster store lookups
-	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_storage_store ON storage(store)")
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to create index: %w", err)
+func loadOrCreateSalt(db *sql.DB) ([]byte, error) {
+	var salt []byte
+	err := db.QueryRow("SELECT value FROM meta WHERE key = 'kdf_salt'").Scan(&salt)
+	if err == nil {
+		return salt, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load kdf salt: %w", err)
 	}
 
-	return &Database{db: db}, nil
+	salt = make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate kdf salt: %w", err)
+	}
+	if _, err := db.Exec("INSERT INTO meta (key, value) VALUES ('kdf_salt', ?)", salt); err != nil {
+		return nil, fmt.Errorf("failed to persist kdf salt: %w", err)
+	}
+	return salt, nil
+}
+
+// loadOrCreateDEK unwraps the database's data encryption key with kek,
+// generating and wrapping a fresh one on first run. Failing to unwrap an
+// existing wrapped key means kek - and so the passphrase it was derived
+// from - is wrong; AES-GCM's authentication tag makes that failure reliable,
+// so there's no need for a separate passphrase-check value.
+func loadOrCreateDEK(db *sql.DB, kek []byte) ([]byte, error) {
+	var wrapped string
+	err := db.QueryRow("SELECT value FROM meta WHERE key = ?", dekMetaKey).Scan(&wrapped)
+	if err == nil {
+		plaintext, err := decryptValue(kek, wrapped)
+		if err != nil {
+			return nil, ErrInvalidPassphrase
+		}
+		return []byte(plaintext), nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load wrapped data encryption key: %w", err)
+	}
+
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("failed to generate data encryption key: %w", err)
+	}
+	sealed, err := encryptValue(kek, string(dek))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data encryption key: %w", err)
+	}
+	if _, err := db.Exec("INSERT INTO meta (key, value) VALUES (?, ?)", dekMetaKey, sealed); err != nil {
+		return nil, fmt.Errorf("failed to persist wrapped data encryption key: %w", err)
+	}
+	return dek, nil
 }
 
 // Close closes the database connection.
@@ -58,16 +211,89 @@ func (d *Database) Close() error {
 	return d.db.Close()
 }
 
-// Get retrieves a value from storage.
-func (d *Database) Get(store, key string) (*string, error) {
+// Grant authorizes token to exercise cap against store. Tokens are opaque to
+// Database - callers mint and distribute them however they see fit (e.g. one
+// per user sharing the database file) - and a capability can be Grant-ed to
+// any number of tokens.
+func (d *Database) Grant(store string, cap Capability, token string) error {
+	if token == "" {
+		return fmt.Errorf("grant requires a non-empty token")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err := d.db.Exec(
+		"INSERT INTO grants (store, capability, token) VALUES (?, ?, ?) ON CONFLICT(store, capability, token) DO NOTHING",
+		store, string(cap), token,
+	)
+	if err != nil {
+		return fmt.Errorf("grant error: %w", err)
+	}
+	return nil
+}
+
+// Authorize reports whether the token carried in ctx (see WithToken) has
+// been Grant-ed cap against store.
+func (d *Database) Authorize(ctx context.Context, store string, cap Capability) (bool, error) {
+	token, ok := tokenFromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.authorizeLocked(store, cap, token)
+}
+
+func (d *Database) authorizeLocked(store string, cap Capability, token string) (bool, error) {
+	var exists int
+	err := d.db.QueryRow(
+		"SELECT 1 FROM grants WHERE store = ? AND capability = ? AND token = ?",
+		store, string(cap), token,
+	).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("authorize query error: %w", err)
+	}
+	return true, nil
+}
+
+// requireLocked denies the call with ErrNotAuthorized unless ctx carries a
+// token Grant-ed cap against store. Every CRUD method below calls this
+// before touching storage.
+func (d *Database) requireLocked(ctx context.Context, store string, cap Capability) error {
+	token, ok := tokenFromContext(ctx)
+	if !ok {
+		return ErrNotAuthorized
+	}
+
+	authorized, err := d.authorizeLocked(store, cap, token)
+	if err != nil {
+		return err
+	}
+	if !authorized {
+		return ErrNotAuthorized
+	}
+	return nil
+}
+
+// Get retrieves a value from storage, decrypting it with store's derived key.
+func (d *Database) Get(ctx context.Context, store, key string) (*string, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	var value string
+	if err := d.requireLocked(ctx, store, CapRead); err != nil {
+		return nil, err
+	}
+
+	var encrypted string
 	err := d.db.QueryRow(
 		"SELECT value FROM storage WHERE store = ? AND key = ?",
 		store, key,
-	).Scan(&value)
+	).Scan(&encrypted)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -76,21 +302,43 @@ func (d *Database) Get(store, key string) (*string, error) {
 		return nil, fmt.Errorf("query error: %w", err)
 	}
 
+	storeKey, err := deriveStoreKey(d.dek, store)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decryptValue(storeKey, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt error: %w", err)
+	}
+
 	return &value, nil
 }
 
-// Set stores a value in storage.
-func (d *Database) Set(store, key, value string) error {
+// Set stores a value in storage, encrypting it with store's derived key.
+func (d *Database) Set(ctx context.Context, store, key, value string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	_, err := d.db.Exec(`
+	if err := d.requireLocked(ctx, store, CapWrite); err != nil {
+		return err
+	}
+
+	storeKey, err := deriveStoreKey(d.dek, store)
+	if err != nil {
+		return err
+	}
+	encrypted, err := encryptValue(storeKey, value)
+	if err != nil {
+		return fmt.Errorf("encrypt error: %w", err)
+	}
+
+	_, err = d.db.Exec(`
 		INSERT INTO storage (store, key, value, updated_at)
 		VALUES (?, ?, ?, strftime('%s', 'now'))
 		ON CONFLICT(store, key) DO UPDATE SET
 			value = excluded.value,
 			updated_at = strftime('%s', 'now')
-	`, store, key, value)
+	`, store, key, encrypted)
 
 	if err != nil {
 		return fmt.Errorf("insert error: %w", err)
@@ -100,10 +348,14 @@ func (d *Database) Set(store, key, value string) error {
 }
 
 // Remove deletes a value from storage.
-func (d *Database) Remove(store, key string) error {
+func (d *Database) Remove(ctx context.Context, store, key string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if err := d.requireLocked(ctx, store, CapWrite); err != nil {
+		return err
+	}
+
 	_, err := d.db.Exec(
 		"DELETE FROM storage WHERE store = ? AND key = ?",
 		store, key,
@@ -117,10 +369,14 @@ func (d *Database) Remove(store, key string) error {
 }
 
 // Has checks if a key exists in storage.
-func (d *Database) Has(store, key string) (bool, error) {
+func (d *Database) Has(ctx context.Context, store, key string) (bool, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if err := d.requireLocked(ctx, store, CapRead); err != nil {
+		return false, err
+	}
+
 	var exists int
 	err := d.db.QueryRow(
 		"SELECT 1 FROM storage WHERE store = ? AND key = ?",
@@ -138,10 +394,14 @@ func (d *Database) Has(store, key string) (bool, error) {
 }
 
 // Clear removes all values in a store.
-func (d *Database) Clear(store string) error {
+func (d *Database) Clear(ctx context.Context, store string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if err := d.requireLocked(ctx, store, CapClear); err != nil {
+		return err
+	}
+
 	_, err := d.db.Exec("DELETE FROM storage WHERE store = ?", store)
 	if err != nil {
 		return fmt.Errorf("delete error: %w", err)
@@ -151,10 +411,14 @@ func (d *Database) Clear(store string) error {
 }
 
 // Keys returns all keys in a store.
-func (d *Database) Keys(store string) ([]string, error) {
+func (d *Database) Keys(ctx context.Context, store string) ([]string, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if err := d.requireLocked(ctx, store, CapRead); err != nil {
+		return nil, err
+	}
+
 	rows, err := d.db.Query("SELECT key FROM storage WHERE store = ?", store)
 	if err != nil {
 		return nil, fmt.Errorf("query error: %w", err)
@@ -176,3 +440,57 @@ func (d *Database) Keys(store string) ([]string, error) {
 
 	return keys, nil
 }
+
+// Rekey re-derives the database's KEK under newPass (with a freshly
+// generated salt), after verifying oldPass unwraps the current data
+// encryption key, and re-wraps that same DEK under the new KEK. No row in
+// the storage table is touched - this is the point of envelope encryption -
+// so rotating the passphrase costs the same whether the database holds ten
+// values or ten million.
+func (d *Database) Rekey(oldPass, newPass string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var wrapped string
+	if err := d.db.QueryRow("SELECT value FROM meta WHERE key = ?", dekMetaKey).Scan(&wrapped); err != nil {
+		return fmt.Errorf("failed to load wrapped data encryption key: %w", err)
+	}
+
+	oldKEK := deriveKEK(oldPass, d.salt)
+	dek, err := decryptValue(oldKEK, wrapped)
+	if err != nil {
+		return ErrInvalidPassphrase
+	}
+
+	newSalt := make([]byte, saltSize)
+	if _, err := rand.Read(newSalt); err != nil {
+		return fmt.Errorf("failed to generate new kdf salt: %w", err)
+	}
+	newKEK := deriveKEK(newPass, newSalt)
+
+	rewrapped, err := encryptValue(newKEK, dek)
+	if err != nil {
+		return fmt.Errorf("failed to re-wrap data encryption key: %w", err)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin rekey transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE meta SET value = ? WHERE key = ?", rewrapped, dekMetaKey); err != nil {
+		return fmt.Errorf("failed to persist re-wrapped data encryption key: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE meta SET value = ? WHERE key = 'kdf_salt'", newSalt); err != nil {
+		return fmt.Errorf("failed to persist new kdf salt: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rekey: %w", err)
+	}
+
+	d.salt = newSalt
+	// d.dek is unchanged - only the KEK wrapping it rotated.
+	return nil
+}
@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+const testToken = "test-token"
+
+func openTestDB(t *testing.T, path, passphrase string) *Database {
+	t.Helper()
+	db, err := New(path, Options{Passphrase: passphrase})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// grantAll gives testToken every capability on store and returns a context
+// carrying it, for tests that don't care about ACL enforcement itself.
+func grantAll(t *testing.T, db *Database, store string) context.Context {
+	t.Helper()
+	for _, cap := range []Capability{CapRead, CapWrite, CapClear} {
+		if err := db.Grant(store, cap, testToken); err != nil {
+			t.Fatalf("Grant(%s): %v", cap, err)
+		}
+	}
+	return WithToken(context.Background(), testToken)
+}
+
+func TestSetGetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+	ctx := grantAll(t, db, "settings")
+
+	if err := db.Set(ctx, "settings", "theme", "dark"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	got, err := db.Get(ctx, "settings", "theme")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got == nil || *got != "dark" {
+		t.Fatalf("got %v, want \"dark\"", got)
+	}
+}
+
+func TestGetMissingKeyReturnsNilNotError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+	ctx := grantAll(t, db, "settings")
+
+	got, err := db.Get(ctx, "settings", "nope")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("got %v, want nil", got)
+	}
+}
+
+func TestWrongPassphraseIsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+	ctx := grantAll(t, db, "settings")
+	if err := db.Set(ctx, "settings", "theme", "dark"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	db.Close()
+
+	if _, err := New(path, Options{Passphrase: "wrong passphrase"}); err != ErrInvalidPassphrase {
+		t.Fatalf("got %v, want ErrInvalidPassphrase", err)
+	}
+}
+
+func TestGrantAndAuthorize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+	ctx := WithToken(context.Background(), testToken)
+
+	if ok, err := db.Authorize(ctx, "fresh-store", CapRead); err != nil || ok {
+		t.Fatalf("expected an ungranted store/capability to be unauthorized, got ok=%v err=%v", ok, err)
+	}
+
+	if err := db.Grant("fresh-store", CapRead, testToken); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+	if ok, err := db.Authorize(ctx, "fresh-store", CapRead); err != nil || !ok {
+		t.Fatalf("expected a granted store/capability to be authorized, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := db.Authorize(ctx, "fresh-store", CapWrite); err != nil || ok {
+		t.Fatalf("expected an ungranted capability on the same store to be unauthorized, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestCRUDDeniesCallsWithoutAToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+
+	if _, err := db.Get(context.Background(), "settings", "theme"); err != ErrNotAuthorized {
+		t.Fatalf("Get with no token: got %v, want ErrNotAuthorized", err)
+	}
+	if err := db.Set(context.Background(), "settings", "theme", "dark"); err != ErrNotAuthorized {
+		t.Fatalf("Set with no token: got %v, want ErrNotAuthorized", err)
+	}
+}
+
+func TestCRUDDeniesCallsWithAnUngrantedToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+	ctx := WithToken(context.Background(), "some-other-token")
+
+	if err := db.Set(ctx, "settings", "theme", "dark"); err != ErrNotAuthorized {
+		t.Fatalf("got %v, want ErrNotAuthorized", err)
+	}
+}
+
+func TestCapabilitiesAreScopedIndependently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+
+	writeOnly := WithToken(context.Background(), "writer")
+	if err := db.Grant("settings", CapWrite, "writer"); err != nil {
+		t.Fatalf("Grant: %v", err)
+	}
+
+	if err := db.Set(writeOnly, "settings", "theme", "dark"); err != nil {
+		t.Fatalf("Set with a write grant: %v", err)
+	}
+	if _, err := db.Get(writeOnly, "settings", "theme"); err != ErrNotAuthorized {
+		t.Fatalf("Get without a read grant: got %v, want ErrNotAuthorized", err)
+	}
+}
+
+func TestStoresAreIsolated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "correct horse battery staple")
+	ctxA := grantAll(t, db, "store-a")
+	ctxB := grantAll(t, db, "store-b")
+
+	if err := db.Set(ctxA, "store-a", "key", "a-value"); err != nil {
+		t.Fatalf("Set store-a: %v", err)
+	}
+	if err := db.Set(ctxB, "store-b", "key", "b-value"); err != nil {
+		t.Fatalf("Set store-b: %v", err)
+	}
+
+	a, err := db.Get(ctxA, "store-a", "key")
+	if err != nil || a == nil || *a != "a-value" {
+		t.Fatalf("store-a: got %v, err %v", a, err)
+	}
+	b, err := db.Get(ctxB, "store-b", "key")
+	if err != nil || b == nil || *b != "b-value" {
+		t.Fatalf("store-b: got %v, err %v", b, err)
+	}
+}
+
+func TestRekeyPreservesDataUnderNewPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "old passphrase")
+	ctx := grantAll(t, db, "settings")
+
+	if err := db.Set(ctx, "settings", "theme", "dark"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if err := db.Rekey("old passphrase", "new passphrase"); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+
+	got, err := db.Get(ctx, "settings", "theme")
+	if err != nil {
+		t.Fatalf("Get after rekey: %v", err)
+	}
+	if got == nil || *got != "dark" {
+		t.Fatalf("got %v, want \"dark\" after rekey", got)
+	}
+	db.Close()
+
+	if _, err := New(path, Options{Passphrase: "old passphrase"}); err != ErrInvalidPassphrase {
+		t.Fatalf("old passphrase should no longer unlock the database, got %v", err)
+	}
+
+	reopened := openTestDB(t, path, "new passphrase")
+	reopenedCtx := WithToken(context.Background(), testToken)
+	got, err = reopened.Get(reopenedCtx, "settings", "theme")
+	if err != nil {
+		t.Fatalf("Get via reopened db: %v", err)
+	}
+	if got == nil || *got != "dark" {
+		t.Fatalf("got %v, want \"dark\" via reopened db", got)
+	}
+}
+
+func TestRekeyRejectsWrongOldPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "old passphrase")
+
+	if err := db.Rekey("not the old passphrase", "new passphrase"); err != ErrInvalidPassphrase {
+		t.Fatalf("got %v, want ErrInvalidPassphrase", err)
+	}
+}
+
+func TestRekeyDoesNotRewriteStorageRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	db := openTestDB(t, path, "old passphrase")
+	ctx := grantAll(t, db, "settings")
+
+	if err := db.Set(ctx, "settings", "theme", "dark"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	var before string
+	if err := db.db.QueryRow("SELECT value FROM storage WHERE store = ? AND key = ?", "settings", "theme").Scan(&before); err != nil {
+		t.Fatalf("read ciphertext before rekey: %v", err)
+	}
+
+	if err := db.Rekey("old passphrase", "new passphrase"); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+
+	var after string
+	if err := db.db.QueryRow("SELECT value FROM storage WHERE store = ? AND key = ?", "settings", "theme").Scan(&after); err != nil {
+		t.Fatalf("read ciphertext after rekey: %v", err)
+	}
+
+	if before != after {
+		t.Fatal("Rekey should only re-wrap the data encryption key, not re-encrypt storage rows")
+	}
+}